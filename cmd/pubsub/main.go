@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"sync"
 
 	ps "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub"
 )
@@ -18,28 +18,15 @@ func main() {
 	// PubSub for custom NewsUpdate struct
 	newsPubSub := ps.NewPubSub[NewsUpdate]()
 
-	// Subscribe to topics
-	stringSub := stringPubSub.Subscribe("general")
-	newsSub := newsPubSub.Subscribe("news")
-
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-
-	// Goroutine to process string messages
-	go func() {
-		defer wg.Done()
-		for msg := range stringSub {
-			fmt.Println("String Subscriber received:", msg)
-		}
-	}()
-
-	// Goroutine to process NewsUpdate messages
-	go func() {
-		defer wg.Done()
-		for news := range newsSub {
-			fmt.Printf("News Subscriber received: Headline - %s, Details - %s\n", news.Headline, news.Details)
-		}
-	}()
+	// Register handlers; each runs on its own bounded worker pool
+	stringHandler := stringPubSub.Handle("general", func(ctx context.Context, msg string) error {
+		fmt.Println("String Subscriber received:", msg)
+		return nil
+	})
+	newsHandler := newsPubSub.Handle("news", func(ctx context.Context, news NewsUpdate) error {
+		fmt.Printf("News Subscriber received: Headline - %s, Details - %s\n", news.Headline, news.Details)
+		return nil
+	})
 
 	// Publish heterogeneous messages
 	stringPubSub.Publish("general", "Hello, World!")
@@ -54,13 +41,12 @@ func main() {
 		Details:  "PubSub system now supports heterogeneous types.",
 	})
 
-	// Unsubscribe and shutdown
-	stringPubSub.Unsubscribe("general", stringSub)
-	newsPubSub.Unsubscribe("news", newsSub)
+	// Stop handlers (draining pending messages) and shutdown
+	stringHandler.Stop()
+	newsHandler.Stop()
 
 	stringPubSub.Shutdown()
 	newsPubSub.Shutdown()
 
-	wg.Wait()
 	fmt.Println("PubSub system shut down gracefully.")
 }
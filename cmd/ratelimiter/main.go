@@ -24,7 +24,9 @@ func main() {
 
 	// Publish messages
 	for i := 0; i < 20; i++ {
-		ps.Publish("news", fmt.Sprintf("Message %d", i))
+		if err := ps.Publish("news", fmt.Sprintf("Message %d", i)); err != nil {
+			fmt.Println("Dropped:", err)
+		}
 		time.Sleep(100 * time.Millisecond) // Simulate a delay between messages
 	}
 
@@ -2,4 +2,7 @@ module github.com/ganeshskudva/Golang-Concurrency-Playground
 
 go 1.23.2
 
-require golang.org/x/time v0.8.0 // indirect
+require (
+	golang.org/x/sync v0.10.0
+	golang.org/x/time v0.8.0 // indirect
+)
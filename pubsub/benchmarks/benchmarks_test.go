@@ -0,0 +1,133 @@
+package benchmarks
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// publishRate is the total number of publishes per second in every workload. It is kept below what
+// every variant delivers to fast subscribers, so that drops come from the dimension being varied
+// rather than from publishers outrunning the brokers.
+const publishRate = 5000
+
+// workload describes one benchmark scenario. Each scenario varies one dimension from the baseline.
+type workload struct {
+	name        string
+	fanOut      int           // Subscribers on the topic
+	publishers  int           // Concurrent publishing goroutines
+	messageSize int           // Payload size in bytes
+	slowRatio   float64       // Fraction of subscribers that are slow
+	slowDelay   time.Duration // Processing time per message for slow subscribers
+}
+
+var workloads = []workload{
+	{name: "baseline", fanOut: 100, publishers: 1, messageSize: 64},
+	{name: "wide-fan-out", fanOut: 1000, publishers: 1, messageSize: 64},
+	{name: "many-publishers", fanOut: 100, publishers: 8, messageSize: 64},
+	{name: "large-messages", fanOut: 100, publishers: 1, messageSize: 4096},
+	{name: "slow-subscribers", fanOut: 100, publishers: 1, messageSize: 64, slowRatio: 0.1, slowDelay: 500 * time.Microsecond},
+}
+
+// BenchmarkBrokers runs every workload against every broker variant at publishRate and reports
+// the time spent in Publish, allocations, p50/p99 delivery latency and the ratio of dropped deliveries.
+func BenchmarkBrokers(b *testing.B) {
+	for _, w := range workloads {
+		for _, v := range Variants() {
+			b.Run(w.name+"/"+v.Name, func(b *testing.B) {
+				runWorkload(b, v.New(), w)
+			})
+		}
+	}
+}
+
+func runWorkload(b *testing.B, broker Broker, w workload) {
+	const topic = "bench"
+	numSlow := int(float64(w.fanOut) * w.slowRatio)
+	histograms := make([]Histogram, w.fanOut)
+	var delivered atomic.Int64
+	var consumers sync.WaitGroup
+	for i := 0; i < w.fanOut; i++ {
+		ch := broker.Subscribe(topic)
+		consumers.Add(1)
+		go func(h *Histogram, slow bool) {
+			defer consumers.Done()
+			for msg := range ch {
+				h.Record(time.Since(msg.Sent))
+				delivered.Add(1)
+				if slow {
+					time.Sleep(w.slowDelay)
+				}
+			}
+		}(&histograms[i], i < numSlow)
+	}
+
+	payload := make([]byte, w.messageSize)
+	restore := silenceStdout(b) // The variants log every drop; keep the terminal usable
+	b.ReportAllocs()
+	b.SetBytes(int64(w.messageSize))
+	b.ResetTimer()
+
+	// Split b.N publishes across the publishers, each publishing at its share of publishRate
+	var publishers sync.WaitGroup
+	var publishTime atomic.Int64
+	interval := time.Second * time.Duration(w.publishers) / publishRate
+	for p := 0; p < w.publishers; p++ {
+		count := b.N / w.publishers
+		if p < b.N%w.publishers {
+			count++
+		}
+		publishers.Add(1)
+		go func(count int) {
+			defer publishers.Done()
+			start := time.Now()
+			for i := 0; i < count; i++ {
+				if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+					time.Sleep(wait)
+				}
+				sent := time.Now()
+				broker.Publish(topic, Message{Sent: sent, Payload: payload})
+				publishTime.Add(int64(time.Since(sent)))
+			}
+		}(count)
+	}
+	publishers.Wait()
+	b.StopTimer()
+
+	broker.Shutdown()
+	consumers.Wait()
+	restore()
+
+	var all Histogram
+	for i := range histograms {
+		all.Merge(&histograms[i])
+	}
+	expected := float64(b.N) * float64(w.fanOut)
+	b.ReportMetric(float64(publishTime.Load())/float64(b.N), "publish-ns/op")
+	b.ReportMetric(float64(all.Percentile(50)), "p50-ns")
+	b.ReportMetric(float64(all.Percentile(99)), "p99-ns")
+	b.ReportMetric(1-float64(delivered.Load())/expected, "drop-ratio")
+}
+
+// silenceStdout redirects os.Stdout to the null device until the returned function is called.
+// The benchmark's cleanup also restores it, so a failing benchmark never leaves it redirected.
+func silenceStdout(b *testing.B) (restore func()) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatalf("open %s: %v", os.DevNull, err)
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+
+	var once sync.Once
+	restore = func() {
+		once.Do(func() {
+			os.Stdout = stdout
+			devNull.Close()
+		})
+	}
+	b.Cleanup(restore)
+	return restore
+}
@@ -0,0 +1,62 @@
+// Package benchmarks runs identical workloads against every broker variant in this repository.
+package benchmarks
+
+import (
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub"
+	deadlockprevention "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/deadlockprevention"
+	ratelimiter "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/ratelimiter"
+	slowsubscriber "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/slowsubscriber"
+	"golang.org/x/time/rate"
+)
+
+// Message is the payload published by the benchmarks.
+// Sent is stamped at publish time so subscribers can measure delivery latency.
+type Message struct {
+	Sent    time.Time
+	Payload []byte
+}
+
+// Broker is the API shared by all variants.
+type Broker interface {
+	Subscribe(topic string) chan Message
+	Unsubscribe(topic string, ch chan Message)
+	Publish(topic string, message Message)
+	Shutdown()
+}
+
+// Variant names a broker implementation and knows how to create it.
+type Variant struct {
+	Name string
+	New  func() Broker
+}
+
+// Variants returns every broker variant. The rate limiter is configured with an infinite
+// rate so that the workloads compare fan-out rather than throttling.
+func Variants() []Variant {
+	return []Variant{
+		{Name: "pubsub", New: func() Broker { return basePubSub{pubsub.NewPubSub[Message]()} }},
+		{Name: "ratelimiter", New: func() Broker { return rateLimiterPubSub{ratelimiter.NewPubSub[Message](rate.Inf, 1)} }},
+		{Name: "slowsubscriber", New: func() Broker { return slowsubscriber.NewPubSub[Message]() }},
+		{Name: "deadlockprevention", New: func() Broker { return deadlockprevention.NewPubSub[Message]() }},
+	}
+}
+
+// basePubSub adapts pubsub.PubSub, whose Publish reports middleware errors, to Broker
+type basePubSub struct {
+	*pubsub.PubSub[Message]
+}
+
+func (b basePubSub) Publish(topic string, message Message) {
+	_ = b.PubSub.Publish(topic, message)
+}
+
+// rateLimiterPubSub adapts ratelimiter.PubSub, whose Publish reports rate limit errors, to Broker
+type rateLimiterPubSub struct {
+	*ratelimiter.PubSub[Message]
+}
+
+func (b rateLimiterPubSub) Publish(topic string, message Message) {
+	_ = b.PubSub.Publish(topic, message)
+}
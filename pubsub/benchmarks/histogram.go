@@ -0,0 +1,80 @@
+package benchmarks
+
+import (
+	"math/bits"
+	"time"
+)
+
+// subBuckets is the number of linear buckets within each power of two.
+// Recorded values are accurate to within 1/subBuckets of their magnitude.
+const subBuckets = 16
+
+// Histogram records durations in log-linear buckets so percentiles can be estimated
+// without keeping every sample. It is not safe for concurrent use; merge per-goroutine histograms.
+type Histogram struct {
+	counts [64 * subBuckets]uint64
+	total  uint64
+}
+
+// Record adds a duration to the histogram. Negative durations count as zero.
+func (h *Histogram) Record(d time.Duration) {
+	h.counts[bucketOf(d)]++
+	h.total++
+}
+
+// Merge adds all samples of other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+}
+
+// Count returns the number of recorded samples.
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Percentile returns an estimate of the p-th percentile (0 < p <= 100).
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(p / 100 * float64(h.total))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return upperBound(i)
+		}
+	}
+	return upperBound(len(h.counts) - 1)
+}
+
+// bucketOf maps a duration to its bucket index
+func bucketOf(d time.Duration) int {
+	if d < subBuckets {
+		if d < 0 {
+			return 0
+		}
+		return int(d)
+	}
+	v := uint64(d)
+	exp := bits.Len64(v) - 1                      // Position of the highest set bit
+	shift := exp - bits.Len64(subBuckets-1)       // Keep log2(subBuckets) bits below it
+	sub := int(v>>uint(shift)) & (subBuckets - 1) // Linear position within the power of two
+	return (shift+1)*subBuckets + sub
+}
+
+// upperBound returns the largest duration that falls into bucket i
+func upperBound(i int) time.Duration {
+	if i < subBuckets {
+		return time.Duration(i)
+	}
+	shift := i/subBuckets - 1
+	sub := i % subBuckets
+	return time.Duration((uint64(subBuckets+sub+1) << uint(shift)) - 1)
+}
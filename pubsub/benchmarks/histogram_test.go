@@ -0,0 +1,38 @@
+package benchmarks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentiles(t *testing.T) {
+	var h Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	for _, tc := range []struct {
+		p        float64
+		expected time.Duration
+	}{
+		{50, 500 * time.Microsecond},
+		{99, 990 * time.Microsecond},
+		{100, 1000 * time.Microsecond},
+	} {
+		got := h.Percentile(tc.p)
+		// Buckets are 1/16th of their magnitude wide
+		if got < tc.expected || got > tc.expected+tc.expected/subBuckets {
+			t.Errorf("p%v: got %v, expected about %v", tc.p, got, tc.expected)
+		}
+	}
+
+	var other Histogram
+	other.Record(-time.Second) // Clamped to zero
+	h.Merge(&other)
+	if h.Count() != 1001 {
+		t.Errorf("Expected 1001 samples after merge, got %d", h.Count())
+	}
+	if got := other.Percentile(50); got != 0 {
+		t.Errorf("Negative sample not clamped: %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Package clock abstracts time so brokers and rate limiters can be driven by a fake clock in tests.
+package clock
+
+import "time"
+
+// Clock provides the current time, timers and tickers.
+type Clock interface {
+	Now() time.Time                         // Current time
+	Since(t time.Time) time.Duration        // Time elapsed since t
+	After(d time.Duration) <-chan time.Time // Channel that receives the time once d has elapsed
+	Sleep(d time.Duration)                  // Block until d has elapsed
+	NewTimer(d time.Duration) Timer         // Timer that fires once after d
+	NewTicker(d time.Duration) Ticker       // Ticker that fires every d
+}
+
+// Timer mirrors *time.Timer with C exposed as a method.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker mirrors *time.Ticker with C exposed as a method.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real returns a Clock backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+// realClock delegates to the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+// realTimer adapts *time.Timer to Timer
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// realTicker adapts *time.Ticker to Ticker
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time   { return t.t.C }
+func (t realTicker) Stop()                 { t.t.Stop() }
+func (t realTicker) Reset(d time.Duration) { t.t.Reset(d) }
@@ -0,0 +1,226 @@
+// Package fakeclock provides a manually advanced clock.Clock for deterministic tests.
+package fakeclock
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
+)
+
+// Clock is a clock.Clock whose time only moves when Advance or Set is called.
+// Timers, tickers, After and Sleep fire synchronously during Advance, in deadline order.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // Broadcast whenever the set of waiters changes
+	now     time.Time
+	waiters []*waiter // Pending timers and tickers
+}
+
+// waiter is a pending timer or ticker
+type waiter struct {
+	deadline time.Time
+	period   time.Duration // Non-zero for tickers
+	ch       chan time.Time
+}
+
+// Compile-time check that Clock satisfies clock.Clock
+var _ clock.Clock = (*Clock)(nil)
+
+// New creates a fake clock set to now.
+func New(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the fake current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the fake time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the fake time once d has elapsed.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Sleep blocks until the clock has been advanced by at least d.
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// NewTimer creates a timer that fires once the clock has advanced by d.
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	t := &fakeTimer{clock: c}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker creates a ticker that fires every time the clock crosses a multiple of d.
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("fakeclock: non-positive interval for NewTicker")
+	}
+	t := &fakeTicker{clock: c}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker that becomes due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.setLocked(c.now.Add(d))
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t, firing every timer and ticker that becomes due. Moving backwards fires nothing.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.setLocked(t)
+	c.mu.Unlock()
+}
+
+// Waiters returns the number of pending timers, tickers, After and Sleep calls.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers, tickers, After or Sleep calls are pending.
+// Use it to make sure a goroutine is waiting on the clock before advancing it.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// setLocked moves time forward step by step so that waiters fire in deadline order
+// and tickers fire once per elapsed period. Must be called with c.mu held.
+func (c *Clock) setLocked(target time.Time) {
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			break
+		}
+
+		w := c.waiters[0]
+		if w.deadline.After(c.now) {
+			c.now = w.deadline
+		}
+		// Deliver like the time package does: never block, drop the tick if the reader is behind
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	if target.After(c.now) {
+		c.now = target
+	}
+	c.cond.Broadcast()
+}
+
+// add registers a waiter. Must be called with c.mu held.
+func (c *Clock) add(w *waiter) {
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+// remove unregisters a waiter and reports whether it was pending. Must be called with c.mu held.
+func (c *Clock) remove(w *waiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer implements clock.Timer on a fake Clock
+type fakeTimer struct {
+	clock *Clock
+	ch    chan time.Time
+	w     *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.w != nil && t.clock.remove(t.w)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	if t.ch == nil {
+		t.ch = make(chan time.Time, 1)
+	}
+	active := t.w != nil && t.clock.remove(t.w)
+	t.w = &waiter{deadline: t.clock.now.Add(d), ch: t.ch}
+	t.clock.add(t.w)
+	if d <= 0 {
+		t.clock.setLocked(t.clock.now) // Fire immediately
+	}
+	return active
+}
+
+// fakeTicker implements clock.Ticker on a fake Clock
+type fakeTicker struct {
+	clock *Clock
+	ch    chan time.Time
+	w     *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	if t.w != nil {
+		t.clock.remove(t.w)
+	}
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("fakeclock: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	if t.ch == nil {
+		t.ch = make(chan time.Time, 1)
+	}
+	if t.w != nil {
+		t.clock.remove(t.w)
+	}
+	t.w = &waiter{deadline: t.clock.now.Add(d), period: d, ch: t.ch}
+	t.clock.add(t.w)
+}
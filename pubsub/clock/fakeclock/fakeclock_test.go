@@ -0,0 +1,73 @@
+package fakeclock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestTimersFireInOrderOnAdvance(t *testing.T) {
+	c := New(epoch)
+
+	late := c.NewTimer(3 * time.Second)
+	early := c.NewTimer(time.Second)
+	stopped := c.NewTimer(2 * time.Second)
+	if !stopped.Stop() {
+		t.Fatal("Stop on a pending timer should return true")
+	}
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-early.C():
+		t.Fatal("Timer fired before its deadline")
+	default:
+	}
+
+	c.Advance(time.Second)
+	if got := <-early.C(); !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("Early timer fired at %v, expected %v", got, epoch.Add(time.Second))
+	}
+	if !c.Now().Equal(epoch.Add(1500 * time.Millisecond)) {
+		t.Errorf("Unexpected time after Advance: %v", c.Now())
+	}
+
+	c.Advance(10 * time.Second)
+	if got := <-late.C(); !got.Equal(epoch.Add(3 * time.Second)) {
+		t.Errorf("Late timer fired at %v", got)
+	}
+	select {
+	case <-stopped.C():
+		t.Error("Stopped timer fired")
+	default:
+	}
+	if c.Waiters() != 0 {
+		t.Errorf("Expected no pending waiters, got %d", c.Waiters())
+	}
+}
+
+func TestTickerAndSleep(t *testing.T) {
+	c := New(epoch)
+
+	ticker := c.NewTicker(time.Second)
+	c.Advance(time.Second)
+	if got := <-ticker.C(); !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("First tick at %v", got)
+	}
+	c.Advance(time.Second)
+	if got := <-ticker.C(); !got.Equal(epoch.Add(2 * time.Second)) {
+		t.Errorf("Second tick at %v", got)
+	}
+	ticker.Stop()
+
+	woke := make(chan time.Time)
+	go func() {
+		c.Sleep(5 * time.Second)
+		woke <- c.Now()
+	}()
+	c.BlockUntil(1) // The sleeper is registered
+	c.Advance(5 * time.Second)
+	if got := <-woke; !got.Equal(epoch.Add(7 * time.Second)) {
+		t.Errorf("Sleeper woke at %v", got)
+	}
+}
@@ -59,3 +59,31 @@ func TestPubSubDeadlockPrevention(t *testing.T) {
 
 	fmt.Println("Test completed without deadlocks.")
 }
+
+func TestUnsubscribeDuringPublish(t *testing.T) {
+	ps := NewPubSub[int]()
+
+	// Publishers keep working from snapshots while subscribers come and go
+	var publisherWg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		publisherWg.Add(1)
+		go func() {
+			defer publisherWg.Done()
+			for j := 0; j < 1000; j++ {
+				ps.Publish("churn", j)
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		ch := ps.Subscribe("churn")
+		go func() {
+			for range ch {
+			}
+		}()
+		ps.Unsubscribe("churn", ch) // Must not race with a send on the closed channel
+	}
+
+	publisherWg.Wait()
+	ps.Shutdown()
+}
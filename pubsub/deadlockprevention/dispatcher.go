@@ -0,0 +1,136 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+)
+
+// queueCapacity is the number of messages a subscriber's dispatcher queue holds
+// before new messages are dropped. It comes on top of the channel buffer.
+const queueCapacity = 100
+
+// subscriber owns a subscription channel and the long-lived dispatcher goroutine that feeds it.
+// Sends happen under the subscriber's lock after checking that it is still open, and only the
+// dispatcher closes the channel, so publishers working from a stale snapshot never send on a closed channel.
+type subscriber[T any] struct {
+	ch chan T // Channel handed out to the consumer
+
+	mu     sync.Mutex
+	queue  []T  // Messages waiting to be sent on ch
+	busy   bool // Set while the dispatcher holds a batch taken from the queue
+	closed bool // Set once the subscription is closing; further messages are ignored
+
+	notify chan struct{} // Signals the dispatcher that the queue is non-empty (capacity 1)
+	quit   chan struct{} // Closed to stop the dispatcher
+	done   chan struct{} // Closed once the dispatcher has closed ch
+}
+
+// newSubscriber creates a subscriber for ch and starts its dispatcher
+func newSubscriber[T any](ch chan T) *subscriber[T] {
+	s := &subscriber[T]{
+		ch:     ch,
+		notify: make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.dispatch()
+	return s
+}
+
+// enqueue hands a message to the subscriber without blocking.
+// The message is dropped if the queue is full or the subscription is closing.
+func (s *subscriber[T]) enqueue(message T) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.queue) >= queueCapacity {
+		s.mu.Unlock()
+		fmt.Println("Subscriber is too slow. Dropping message.")
+		return
+	}
+	// Fast path: nothing is pending, so sending directly keeps ordering and skips the dispatcher
+	if len(s.queue) == 0 && !s.busy {
+		select {
+		case s.ch <- message:
+			s.mu.Unlock()
+			return
+		default:
+		}
+	}
+	s.queue = append(s.queue, message)
+	s.mu.Unlock()
+
+	// Wake the dispatcher if it is idle
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch forwards queued messages to the subscriber channel until the subscription is closed
+func (s *subscriber[T]) dispatch() {
+	defer close(s.done)
+	defer close(s.ch)
+
+	var batch []T
+	for {
+		select {
+		case <-s.notify:
+		case <-s.quit:
+			s.flush(nil)
+			return
+		}
+
+		// Take the whole queue at once, handing our empty slice back for reuse
+		s.mu.Lock()
+		batch, s.queue = s.queue, batch[:0]
+		s.busy = true
+		s.mu.Unlock()
+
+		for i, message := range batch {
+			select {
+			case s.ch <- message:
+			case <-s.quit:
+				s.flush(batch[i:])
+				return
+			}
+		}
+		clear(batch) // Release references held by the reused slice
+
+		s.mu.Lock()
+		s.busy = false
+		s.mu.Unlock()
+	}
+}
+
+// flush moves pending messages into the channel buffer without blocking before the channel is closed.
+// Messages that do not fit are dropped, so a consumer that stopped reading cannot leak the dispatcher.
+func (s *subscriber[T]) flush(pending []T) {
+	s.mu.Lock()
+	pending = append(pending, s.queue...)
+	s.queue = nil
+	s.mu.Unlock()
+
+	for _, message := range pending {
+		select {
+		case s.ch <- message:
+		default:
+			return
+		}
+	}
+}
+
+// close stops accepting messages and tells the dispatcher to exit. Use wait to block until ch is closed.
+func (s *subscriber[T]) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	close(s.quit)
+}
+
+// wait blocks until the dispatcher has closed the subscriber channel
+func (s *subscriber[T]) wait() {
+	<-s.done
+}
@@ -0,0 +1,206 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// queueCapacity is the number of messages a subscriber's dispatcher queue holds
+// before new messages are dropped. It comes on top of the channel buffer.
+const queueCapacity = 100
+
+// subscriber owns a subscription channel and the long-lived dispatcher goroutine that feeds it.
+// Publish sends directly when nothing is pending and the channel has room; otherwise it appends
+// to the queue and the dispatcher moves queued messages into the channel in order.
+type subscriber[T any] struct {
+	ch       chan T                         // Channel handed out to the consumer
+	deliver  atomic.Pointer[DeliverFunc[T]] // Delivery middleware composed around enqueue
+	inFlight *inFlight                      // Limits sends blocked on a full channel; nil if unlimited
+
+	mu     sync.Mutex
+	queue  []T  // Messages waiting to be sent on ch
+	busy   bool // Set while the dispatcher holds a batch taken from the queue
+	closed bool // Set once the subscription is closing; further messages are rejected
+	latest bool // Set for mailboxes, which keep only the newest message in ch and have no dispatcher
+
+	notify chan struct{} // Signals the dispatcher that the queue is non-empty (capacity 1)
+	quit   chan struct{} // Closed to stop the dispatcher
+	done   chan struct{} // Closed once the dispatcher has closed ch
+
+	ctx    context.Context    // Canceled with quit so that a dispatcher queued for the in-flight limit stops waiting
+	cancel context.CancelFunc // Cancels ctx
+}
+
+// newSubscriber creates a subscriber for ch and starts its dispatcher.
+// inFlight may be nil for no in-flight limit.
+func newSubscriber[T any](ch chan T, inFlight *inFlight) *subscriber[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &subscriber[T]{
+		ch:       ch,
+		inFlight: inFlight,
+		notify:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go s.dispatch()
+	return s
+}
+
+// deliverFunc returns the subscriber's current delivery chain
+func (s *subscriber[T]) deliverFunc() DeliverFunc[T] {
+	return *s.deliver.Load()
+}
+
+// setDeliver replaces the subscriber's delivery chain
+func (s *subscriber[T]) setDeliver(deliver DeliverFunc[T]) {
+	s.deliver.Store(&deliver)
+}
+
+// enqueue hands a message to the subscriber without blocking.
+// Returns ErrDropped if the queue is full and ErrClosed if the subscription is closing.
+func (s *subscriber[T]) enqueue(message T) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	if s.latest {
+		s.replace(message)
+		s.mu.Unlock()
+		return nil
+	}
+	if len(s.queue) >= queueCapacity {
+		s.mu.Unlock()
+		// Queue is full; drop the message to avoid blocking
+		fmt.Println("Subscriber is too slow. Dropping message.")
+		return ErrDropped
+	}
+	// Fast path: nothing is pending, so sending directly keeps ordering and skips the dispatcher
+	if len(s.queue) == 0 && !s.busy {
+		select {
+		case s.ch <- message:
+			s.mu.Unlock()
+			return nil
+		default:
+		}
+	}
+	s.queue = append(s.queue, message)
+	s.mu.Unlock()
+
+	// Wake the dispatcher if it is idle
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// dispatch forwards queued messages to the subscriber channel until the subscription is closed
+func (s *subscriber[T]) dispatch() {
+	defer close(s.done)
+	defer close(s.ch)
+
+	var batch []T
+	for {
+		select {
+		case <-s.notify:
+		case <-s.quit:
+			s.flush(nil)
+			return
+		}
+
+		// Take the whole queue at once, handing our empty slice back for reuse
+		s.mu.Lock()
+		batch, s.queue = s.queue, batch[:0]
+		s.busy = true
+		s.mu.Unlock()
+
+		for i, message := range batch {
+			if !s.send(message) {
+				s.flush(batch[i:])
+				return
+			}
+		}
+		clear(batch) // Release references held by the reused slice
+
+		s.mu.Lock()
+		s.busy = false
+		s.mu.Unlock()
+	}
+}
+
+// send sends a message on ch, blocking while the channel is full. A blocked send counts against
+// the in-flight limit; under InFlightReject the message is dropped instead of waiting.
+// Returns false if the subscription was closed before the message was sent.
+func (s *subscriber[T]) send(message T) bool {
+	if s.inFlight == nil {
+		select {
+		case s.ch <- message:
+			return true
+		case <-s.quit:
+			return false
+		}
+	}
+
+	select {
+	case s.ch <- message:
+		return true // Did not block, so nothing was outstanding
+	default:
+	}
+
+	if err := s.inFlight.acquire(s.ctx, 1); err != nil {
+		if err == ErrInFlightLimit {
+			fmt.Println("In-flight limit reached. Dropping message.")
+			return true
+		}
+		return false // Closed while queued
+	}
+	defer s.inFlight.release(1)
+
+	select {
+	case s.ch <- message:
+		return true
+	case <-s.quit:
+		return false
+	}
+}
+
+// flush moves pending messages into the channel buffer without blocking before the channel is closed.
+// Messages that do not fit are dropped, so a consumer that stopped reading cannot leak the dispatcher.
+func (s *subscriber[T]) flush(pending []T) {
+	s.mu.Lock()
+	pending = append(pending, s.queue...)
+	s.queue = nil
+	s.mu.Unlock()
+
+	for _, message := range pending {
+		select {
+		case s.ch <- message:
+		default:
+			return
+		}
+	}
+}
+
+// close stops accepting messages and tells the dispatcher to exit. Use wait to block until ch is closed.
+func (s *subscriber[T]) close() {
+	s.mu.Lock()
+	s.closed = true
+	if s.latest {
+		// Mailboxes have no dispatcher; holding mu guarantees no publisher is sending
+		close(s.ch)
+		close(s.done)
+	}
+	s.mu.Unlock()
+	close(s.quit)
+	s.cancel()
+}
+
+// wait blocks until the dispatcher has closed the subscriber channel
+func (s *subscriber[T]) wait() {
+	<-s.done
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDispatcherPreservesOrderWithoutGoroutinePerMessage(t *testing.T) {
+	ps := NewPubSub[int]()
+
+	const numSubscribers = 100
+	const numMessages = 150 // More than the channel buffer, less than buffer plus queue
+
+	subscribers := make([]chan int, numSubscribers)
+	for i := range subscribers {
+		subscribers[i] = ps.Subscribe("ordered")
+	}
+	baseline := runtime.NumGoroutine()
+
+	// Nobody is reading yet, so messages pile up in the channels and queues
+	for i := 0; i < numMessages; i++ {
+		ps.Publish("ordered", i)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("Publish spawned goroutines: %d before, %d after", baseline, n)
+	}
+
+	for i, ch := range subscribers {
+		for expected := 0; expected < numMessages; expected++ {
+			if got := <-ch; got != expected {
+				t.Fatalf("Subscriber %d: got message %d, expected %d", i, got, expected)
+			}
+		}
+	}
+	ps.Shutdown()
+}
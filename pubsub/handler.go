@@ -0,0 +1,257 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HandlerFunc processes a single message delivered on a topic.
+type HandlerFunc[T any] func(ctx context.Context, msg T) error
+
+// HandleOption configures a handler registered with Handle.
+type HandleOption func(*handleConfig)
+
+// DefaultGracePeriod is how long a handler may keep running after its subscription is closed
+// when WithGracePeriod is not given.
+const DefaultGracePeriod = 10 * time.Second
+
+// handleConfig holds the settings collected from HandleOptions
+type handleConfig struct {
+	concurrency int                           // Number of worker goroutines
+	onError     func(topic string, err error) // Called for every failed or panicking invocation
+	weight      int64                         // In-flight limit units held by each invocation
+	gracePeriod time.Duration                 // Time allowed for draining before the context is canceled
+}
+
+// WithConcurrency sets how many messages may be handled at the same time.
+// Values below 1 are treated as 1.
+func WithConcurrency(n int) HandleOption {
+	return func(c *handleConfig) {
+		if n < 1 {
+			n = 1
+		}
+		c.concurrency = n
+	}
+}
+
+// WithErrorHandler registers a callback invoked with every handler failure, including recovered panics.
+func WithErrorHandler(fn func(topic string, err error)) HandleOption {
+	return func(c *handleConfig) {
+		c.onError = fn
+	}
+}
+
+// WithWeight sets how much of the broker's in-flight limit (see WithInFlightLimit) each invocation holds,
+// so that expensive handlers can count for more than one delivery. Values below 1 are treated as 1.
+func WithWeight(n int64) HandleOption {
+	return func(c *handleConfig) {
+		if n < 1 {
+			n = 1
+		}
+		c.weight = n
+	}
+}
+
+// WithGracePeriod sets how long workers may keep handling in-flight and buffered messages after
+// the subscription is closed before the context passed to the handler is canceled.
+func WithGracePeriod(d time.Duration) HandleOption {
+	return func(c *handleConfig) {
+		c.gracePeriod = d
+	}
+}
+
+// PanicError is reported when a handler panics. The panic is recovered so the worker keeps running.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace captured at the point of recovery
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pubsub: handler panicked: %v", e.Value)
+}
+
+// HandlerStats is a point-in-time snapshot of a handler's activity.
+type HandlerStats struct {
+	Handled      uint64        // Invocations that returned without error
+	Failed       uint64        // Invocations that returned an error (panics included)
+	Panicked     uint64        // Invocations that panicked
+	TotalLatency time.Duration // Sum of the time spent in all invocations
+	MaxLatency   time.Duration // Slowest single invocation
+}
+
+// AvgLatency returns the mean time spent per invocation.
+func (s HandlerStats) AvgLatency() time.Duration {
+	total := s.Handled + s.Failed
+	if total == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(total)
+}
+
+// Handler is a subscription whose messages are processed by a bounded pool of workers.
+type Handler[T any] struct {
+	ps     *PubSub[T]
+	topic  string
+	ch     chan T
+	fn     HandlerFunc[T]
+	cfg    handleConfig
+	ctx    context.Context    // Passed to every invocation; canceled once the workers exit or the grace period ends
+	cancel context.CancelFunc // Cancels ctx
+	wg     sync.WaitGroup     // Tracks the worker goroutines
+	done   chan struct{}      // Closed when every worker has exited
+
+	closeOnce sync.Once
+	closed    chan struct{} // Closed once the subscription is known to be closed; starts the grace period
+
+	handled      atomic.Uint64
+	failed       atomic.Uint64
+	panicked     atomic.Uint64
+	totalLatency atomic.Int64
+	maxLatency   atomic.Int64
+}
+
+// Handle subscribes to topic and runs fn for every message on a bounded pool of workers.
+// Workers exit once the subscription is closed by Stop or Shutdown, after draining any messages
+// already buffered. The context passed to fn stays live while they drain, and is canceled once
+// the grace period (see WithGracePeriod) has passed, so that long-running invocations can give up.
+func (ps *PubSub[T]) Handle(topic string, fn HandlerFunc[T], opts ...HandleOption) *Handler[T] {
+	cfg := handleConfig{concurrency: 1, weight: 1, gracePeriod: DefaultGracePeriod}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handler[T]{
+		ps:     ps,
+		topic:  topic,
+		ch:     ps.Subscribe(topic),
+		fn:     fn,
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	// Start a fixed number of workers; they share the subscription channel
+	h.wg.Add(cfg.concurrency)
+	for i := 0; i < cfg.concurrency; i++ {
+		go h.work()
+	}
+
+	go func() {
+		h.wg.Wait()
+		h.cancel()
+		close(h.done)
+	}()
+	go h.expire()
+	return h
+}
+
+// Stop unsubscribes the handler and waits for in-flight and buffered messages to be handled.
+// Invocations still running when the grace period ends see their context canceled.
+func (h *Handler[T]) Stop() {
+	h.ps.Unsubscribe(h.topic, h.ch)
+	h.markClosed()
+	<-h.done
+}
+
+// markClosed records that the subscription is closed, starting the grace period
+func (h *Handler[T]) markClosed() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// expire cancels ctx once the grace period after the subscription closed has passed,
+// unless every worker has exited first
+func (h *Handler[T]) expire() {
+	select {
+	case <-h.closed:
+	case <-h.done:
+		return
+	}
+
+	timer := h.ps.clock.NewTimer(h.cfg.gracePeriod)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		h.cancel()
+	case <-h.done:
+	}
+}
+
+// Done returns a channel that is closed once all workers have exited.
+func (h *Handler[T]) Done() <-chan struct{} {
+	return h.done
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *Handler[T]) Stats() HandlerStats {
+	return HandlerStats{
+		Handled:      h.handled.Load(),
+		Failed:       h.failed.Load(),
+		Panicked:     h.panicked.Load(),
+		TotalLatency: time.Duration(h.totalLatency.Load()),
+		MaxLatency:   time.Duration(h.maxLatency.Load()),
+	}
+}
+
+// work consumes messages until the subscription channel is closed.
+// The first worker to find it closed starts the grace period, which also covers Shutdown.
+func (h *Handler[T]) work() {
+	defer h.wg.Done()
+	defer h.markClosed()
+	for msg := range h.ch {
+		if limit := h.ps.inFlight; limit != nil {
+			if err := limit.acquire(h.ctx, h.cfg.weight); err != nil {
+				h.record(0, err) // Rejected without invoking the handler
+				continue
+			}
+			h.handle(msg)
+			limit.release(h.cfg.weight)
+			continue
+		}
+		h.handle(msg)
+	}
+}
+
+// handle invokes the handler for msg and records the outcome
+func (h *Handler[T]) handle(msg T) {
+	start := h.ps.clock.Now()
+	err := h.invoke(msg)
+	h.record(h.ps.clock.Since(start), err)
+}
+
+// invoke calls the handler, converting a panic into a *PanicError
+func (h *Handler[T]) invoke(msg T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.panicked.Add(1)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return h.fn(h.ctx, msg)
+}
+
+// record updates the counters for a finished invocation and reports failures
+func (h *Handler[T]) record(latency time.Duration, err error) {
+	h.totalLatency.Add(int64(latency))
+	for {
+		current := h.maxLatency.Load()
+		if int64(latency) <= current || h.maxLatency.CompareAndSwap(current, int64(latency)) {
+			break
+		}
+	}
+
+	if err == nil {
+		h.handled.Add(1)
+		return
+	}
+	h.failed.Add(1)
+	if h.cfg.onError != nil {
+		h.cfg.onError(h.topic, err)
+	}
+}
@@ -0,0 +1,176 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+)
+
+func TestHandleBoundedConcurrency(t *testing.T) {
+	ps := NewPubSub[int]()
+
+	const concurrency = 4
+	const numMessages = 50
+
+	var inFlight, peak atomic.Int32
+	h := ps.Handle("jobs", func(ctx context.Context, msg int) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond) // Simulate work
+		return nil
+	}, WithConcurrency(concurrency))
+
+	for i := 0; i < numMessages; i++ {
+		ps.Publish("jobs", i)
+	}
+	h.Stop()
+
+	stats := h.Stats()
+	if stats.Handled != numMessages {
+		t.Errorf("Expected %d handled messages, got %d", numMessages, stats.Handled)
+	}
+	if p := peak.Load(); p > concurrency {
+		t.Errorf("Concurrency limit exceeded: peak %d, limit %d", p, concurrency)
+	}
+	if stats.MaxLatency < 5*time.Millisecond || stats.AvgLatency() == 0 {
+		t.Errorf("Latency not recorded: %+v", stats)
+	}
+}
+
+func TestHandleRecoversPanicsAndReportsFailures(t *testing.T) {
+	ps := NewPubSub[string]()
+
+	var mu sync.Mutex
+	var reported []error
+	h := ps.Handle("events", func(ctx context.Context, msg string) error {
+		switch msg {
+		case "panic":
+			panic("boom")
+		case "fail":
+			return errors.New("failed")
+		}
+		return nil
+	}, WithErrorHandler(func(topic string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	}))
+
+	for _, msg := range []string{"ok", "panic", "fail", "ok"} {
+		ps.Publish("events", msg)
+	}
+
+	// Shutdown closes the subscription; the handler drains and exits
+	ps.Shutdown()
+	select {
+	case <-h.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Handler did not exit after Shutdown")
+	}
+
+	stats := h.Stats()
+	if stats.Handled != 2 || stats.Failed != 2 || stats.Panicked != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var panicErr *PanicError
+	if len(reported) != 2 || !errors.As(errors.Join(reported...), &panicErr) {
+		t.Errorf("Expected a failure and a PanicError to be reported, got %v", reported)
+	}
+}
+
+func TestHandleLatencyUsesClock(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[time.Duration](WithClock(clk))
+
+	// Each message takes as long as its value, measured on the fake clock
+	h := ps.Handle("jobs", func(ctx context.Context, d time.Duration) error {
+		clk.Advance(d)
+		return nil
+	})
+	for _, d := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond} {
+		ps.Publish("jobs", d)
+	}
+	h.Stop()
+
+	stats := h.Stats()
+	if stats.TotalLatency != 40*time.Millisecond || stats.MaxLatency != 30*time.Millisecond {
+		t.Errorf("Expected total 40ms and max 30ms, got %+v", stats)
+	}
+	if avg := stats.AvgLatency(); avg != 20*time.Millisecond {
+		t.Errorf("Expected average latency 20ms, got %v", avg)
+	}
+}
+
+func TestHandleDrainsWithLiveContextAfterStop(t *testing.T) {
+	ps := NewPubSub[int]()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	h := ps.Handle("jobs", func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+			<-release // Still running when Stop is called
+		}
+		return ctx.Err()
+	})
+	for i := 0; i < 4; i++ {
+		ps.Publish("jobs", i)
+	}
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		h.Stop()
+	}()
+	close(release)
+	<-stopped
+
+	if stats := h.Stats(); stats.Handled != 4 || stats.Failed != 0 {
+		t.Errorf("Expected the buffered messages to be handled with a live context, got %+v", stats)
+	}
+}
+
+func TestHandleCancelsContextAfterGracePeriod(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk))
+
+	started := make(chan struct{})
+	h := ps.Handle("jobs", func(ctx context.Context, msg int) error {
+		close(started)
+		<-ctx.Done() // Blocks until the grace period is over
+		return ctx.Err()
+	}, WithConcurrency(2), WithGracePeriod(time.Second))
+	ps.Publish("jobs", 1)
+	<-started
+
+	ps.Shutdown() // The idle worker finds the subscription closed and starts the grace period
+	clk.BlockUntil(1)
+	select {
+	case <-h.Done():
+		t.Fatal("Expected the handler to keep running during the grace period")
+	default:
+	}
+	clk.Advance(time.Second)
+	select {
+	case <-h.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the blocked handler to observe the canceled context after the grace period")
+	}
+	if stats := h.Stats(); stats.Failed != 1 {
+		t.Errorf("Expected the canceled invocation to be recorded as failed, got %+v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrInFlightLimit is reported for a delivery rejected because the in-flight limit was reached.
+var ErrInFlightLimit = errors.New("pubsub: in-flight limit reached")
+
+// InFlightPolicy selects what happens to a delivery when the in-flight limit is reached.
+type InFlightPolicy int
+
+const (
+	// InFlightQueue waits, in arrival order, until enough deliveries have finished.
+	InFlightQueue InFlightPolicy = iota
+	// InFlightReject drops the delivery and reports ErrInFlightLimit.
+	InFlightReject
+)
+
+// WithInFlightLimit caps the total weight of deliveries outstanding at the same time across the broker:
+// handler invocations (weight 1 unless set with WithWeight) and sends blocked on a full subscriber channel
+// (weight 1). Deliveries that complete immediately do not count.
+func WithInFlightLimit(limit int64, policy InFlightPolicy) Option {
+	return func(o *options) {
+		o.inFlightLimit = limit
+		o.inFlightPolicy = policy
+	}
+}
+
+// InFlightStats is a point-in-time snapshot of the in-flight limiter.
+type InFlightStats struct {
+	Limit     int64         // Maximum total weight
+	InFlight  int64         // Weight currently held
+	Acquired  uint64        // Deliveries admitted
+	Queued    uint64        // Deliveries that had to wait to be admitted
+	Rejected  uint64        // Deliveries dropped by InFlightReject or too heavy to ever fit
+	TotalWait time.Duration // Time admitted deliveries spent waiting
+	MaxWait   time.Duration // Longest single wait
+}
+
+// AvgWait returns the mean wait per admitted delivery.
+func (s InFlightStats) AvgWait() time.Duration {
+	if s.Acquired == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Acquired)
+}
+
+// InFlightStats returns a snapshot of the in-flight limiter, or zero stats if WithInFlightLimit was not given.
+func (ps *PubSub[T]) InFlightStats() InFlightStats {
+	if ps.inFlight == nil {
+		return InFlightStats{}
+	}
+	return ps.inFlight.stats()
+}
+
+// inFlight limits the weight of outstanding deliveries with a weighted semaphore
+type inFlight struct {
+	sem    *semaphore.Weighted // Waiters are served in FIFO order
+	limit  int64               // Size of sem
+	policy InFlightPolicy
+	clock  clock.Clock
+
+	inUse     atomic.Int64
+	acquired  atomic.Uint64
+	queued    atomic.Uint64
+	rejected  atomic.Uint64
+	totalWait atomic.Int64
+	maxWait   atomic.Int64
+}
+
+func newInFlight(limit int64, policy InFlightPolicy, c clock.Clock) *inFlight {
+	return &inFlight{sem: semaphore.NewWeighted(limit), limit: limit, policy: policy, clock: c}
+}
+
+// acquire admits a delivery of weight n. It returns ErrInFlightLimit if the delivery is rejected,
+// or ctx.Err() if ctx is done while queueing.
+func (f *inFlight) acquire(ctx context.Context, n int64) error {
+	if n > f.limit {
+		f.rejected.Add(1)
+		return ErrInFlightLimit // Would wait forever
+	}
+	if !f.sem.TryAcquire(n) {
+		if f.policy == InFlightReject {
+			f.rejected.Add(1)
+			return ErrInFlightLimit
+		}
+
+		f.queued.Add(1)
+		start := f.clock.Now()
+		if err := f.sem.Acquire(ctx, n); err != nil {
+			return err
+		}
+		wait := int64(f.clock.Since(start))
+		f.totalWait.Add(wait)
+		for {
+			current := f.maxWait.Load()
+			if wait <= current || f.maxWait.CompareAndSwap(current, wait) {
+				break
+			}
+		}
+	}
+
+	f.acquired.Add(1)
+	f.inUse.Add(n)
+	return nil
+}
+
+// release ends a delivery of weight n
+func (f *inFlight) release(n int64) {
+	f.inUse.Add(-n)
+	f.sem.Release(n)
+}
+
+func (f *inFlight) stats() InFlightStats {
+	return InFlightStats{
+		Limit:     f.limit,
+		InFlight:  f.inUse.Load(),
+		Acquired:  f.acquired.Load(),
+		Queued:    f.queued.Load(),
+		Rejected:  f.rejected.Load(),
+		TotalWait: time.Duration(f.totalWait.Load()),
+		MaxWait:   time.Duration(f.maxWait.Load()),
+	}
+}
@@ -0,0 +1,191 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+)
+
+// waitForStats polls the in-flight stats until cond holds or a second has passed
+func waitForStats[T any](t *testing.T, ps *PubSub[T], cond func(InFlightStats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond(ps.InFlightStats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met, stats: %+v", ps.InFlightStats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInFlightLimitSharedByHandlers(t *testing.T) {
+	const limit = 3
+	ps := NewPubSub[int](WithInFlightLimit(limit, InFlightQueue))
+
+	var inFlight, peak atomic.Int64
+	fn := func(ctx context.Context, msg int) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond) // Simulate work
+		return nil
+	}
+	a := ps.Handle("a", fn, WithConcurrency(4))
+	b := ps.Handle("b", fn, WithConcurrency(4))
+
+	for i := 0; i < 20; i++ {
+		ps.Publish("a", i)
+		ps.Publish("b", i)
+	}
+	a.Stop()
+	b.Stop()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("In-flight limit exceeded: peak %d, limit %d", p, limit)
+	}
+	if handled := a.Stats().Handled + b.Stats().Handled; handled != 40 {
+		t.Errorf("Expected 40 handled messages, got %d", handled)
+	}
+	stats := ps.InFlightStats()
+	if stats.Limit != limit || stats.InFlight != 0 || stats.Acquired != 40 || stats.Rejected != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.Queued == 0 {
+		t.Errorf("Expected some deliveries to queue, stats: %+v", stats)
+	}
+}
+
+func TestInFlightWeight(t *testing.T) {
+	ps := NewPubSub[int](WithInFlightLimit(4, InFlightQueue))
+
+	var inFlight, peak atomic.Int64
+	h := ps.Handle("jobs", func(ctx context.Context, msg int) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}, WithConcurrency(4), WithWeight(2))
+
+	for i := 0; i < 20; i++ {
+		ps.Publish("jobs", i)
+	}
+	h.Stop()
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("Expected at most 2 invocations of weight 2 under a limit of 4, got %d", p)
+	}
+	if handled := h.Stats().Handled; handled != 20 {
+		t.Errorf("Expected 20 handled messages, got %d", handled)
+	}
+}
+
+func TestInFlightRejectReportsError(t *testing.T) {
+	ps := NewPubSub[string](WithInFlightLimit(1, InFlightReject))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var reported []error
+	h := ps.Handle("jobs", func(ctx context.Context, msg string) error {
+		if msg == "slow" {
+			close(started)
+			<-release
+		}
+		return nil
+	}, WithConcurrency(2), WithErrorHandler(func(topic string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	}))
+
+	ps.Publish("jobs", "slow")
+	<-started
+	ps.Publish("jobs", "rejected") // The only slot is held by the slow invocation
+	waitForStats(t, ps, func(s InFlightStats) bool { return s.Rejected == 1 })
+	close(release)
+	h.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || !errors.Is(reported[0], ErrInFlightLimit) {
+		t.Errorf("Expected ErrInFlightLimit to be reported, got %v", reported)
+	}
+	if stats := h.Stats(); stats.Handled != 1 || stats.Failed != 1 {
+		t.Errorf("Expected 1 handled and 1 failed, got %+v", stats)
+	}
+}
+
+func TestInFlightWaitMetrics(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[string](WithClock(clk), WithInFlightLimit(1, InFlightQueue))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := ps.Handle("jobs", func(ctx context.Context, msg string) error {
+		if msg == "slow" {
+			close(started)
+			<-release
+		}
+		return nil
+	}, WithConcurrency(2))
+
+	ps.Publish("jobs", "slow")
+	<-started
+	ps.Publish("jobs", "queued")
+	waitForStats(t, ps, func(s InFlightStats) bool { return s.Queued == 1 })
+
+	clk.Advance(3 * time.Second)
+	close(release)
+	h.Stop()
+
+	stats := ps.InFlightStats()
+	if stats.Acquired != 2 || stats.TotalWait != 3*time.Second || stats.MaxWait != 3*time.Second {
+		t.Errorf("Unexpected wait metrics: %+v", stats)
+	}
+	if avg := stats.AvgWait(); avg != 1500*time.Millisecond {
+		t.Errorf("Expected an average wait of 1.5s, got %v", avg)
+	}
+}
+
+func TestInFlightLimitsBlockedSends(t *testing.T) {
+	ps := NewPubSub[int](WithInFlightLimit(1, InFlightReject))
+
+	// Neither subscriber reads, so both dispatchers block once the channels are full
+	first := ps.Subscribe("events")
+	second := ps.Subscribe("events")
+	for i := 0; i < 101; i++ {
+		ps.Publish("events", i)
+	}
+
+	// One dispatcher holds the only slot while blocked; the other drops its message
+	waitForStats(t, ps, func(s InFlightStats) bool { return s.InFlight == 1 && s.Rejected == 1 })
+
+	ps.Unsubscribe("events", first)
+	ps.Unsubscribe("events", second)
+	if stats := ps.InFlightStats(); stats.InFlight != 0 {
+		t.Errorf("Expected the slot to be released on Unsubscribe, stats: %+v", stats)
+	}
+}
+
+func TestInFlightStatsWithoutLimit(t *testing.T) {
+	ps := NewPubSub[int]()
+	if stats := ps.InFlightStats(); stats != (InFlightStats{}) {
+		t.Errorf("Expected zero stats without a limit, got %+v", stats)
+	}
+}
@@ -0,0 +1,45 @@
+package pubsub
+
+import "context"
+
+// SubscribeLatest subscribes to topic with a mailbox: a channel that holds at most one message.
+// Publishing replaces the pending message, if the consumer has not taken it yet, so the consumer
+// always reads the newest value and never a backlog of stale ones. Nothing is ever dropped as
+// too slow, and no goroutine runs for the subscription: Publish swaps the value in place.
+//
+// The channel is closed by Unsubscribe or Shutdown like any other subscription.
+func (ps *PubSub[T]) SubscribeLatest(topic string) chan T {
+	ch := make(chan T, 1)
+	ps.add(topic, newMailbox(ch))
+	return ch
+}
+
+// newMailbox creates a subscriber that keeps only the newest message in ch
+func newMailbox[T any](ch chan T) *subscriber[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subscriber[T]{
+		ch:     ch,
+		latest: true,
+		notify: make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// replace puts message in the mailbox, discarding the message it holds if the consumer has not taken it.
+// Must be called with s.mu held, which serializes publishers; the consumer can only make room.
+func (s *subscriber[T]) replace(message T) {
+	select {
+	case s.ch <- message:
+		return
+	default:
+	}
+
+	select {
+	case <-s.ch: // Discard the stale message
+	default: // The consumer took it meanwhile
+	}
+	s.ch <- message // Cannot block: the mailbox is empty and only publishers holding s.mu send
+}
@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeLatestKeepsNewestValue(t *testing.T) {
+	ps := NewPubSub[int]()
+	ch := ps.SubscribeLatest("ui")
+
+	for i := 1; i <= 100; i++ {
+		ps.Publish("ui", i)
+	}
+	if got := <-ch; got != 100 {
+		t.Errorf("Expected the newest value 100, got %d", got)
+	}
+	if len(ch) != 0 {
+		t.Errorf("Expected the mailbox to be empty after reading, holds %d", len(ch))
+	}
+
+	ps.Publish("ui", 101)
+	if got := <-ch; got != 101 {
+		t.Errorf("Expected 101, got %d", got)
+	}
+
+	ps.Unsubscribe("ui", ch)
+	if _, ok := <-ch; ok {
+		t.Error("Expected the mailbox channel to be closed")
+	}
+}
+
+func TestSubscribeLatestStartsNoGoroutines(t *testing.T) {
+	ps := NewPubSub[int]()
+	before := runtime.NumGoroutine()
+
+	var mailboxes []chan int
+	for i := 0; i < 100; i++ {
+		mailboxes = append(mailboxes, ps.SubscribeLatest("ui"))
+	}
+	for i := 0; i < 1000; i++ {
+		ps.Publish("ui", i)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected no new goroutines, had %d, now %d", before, after)
+	}
+	for _, ch := range mailboxes {
+		if got := <-ch; got != 999 {
+			t.Fatalf("Expected 999, got %d", got)
+		}
+	}
+	ps.Shutdown()
+}
+
+func TestSubscribeLatestConcurrentReader(t *testing.T) {
+	ps := NewPubSub[int]()
+	ch := ps.SubscribeLatest("ui")
+
+	// The reader never sees a value older than one it has already read
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		last := -1
+		for v := range ch {
+			if v <= last {
+				t.Errorf("Read %d after %d", v, last)
+				return
+			}
+			last = v
+		}
+	}()
+
+	for i := 0; i < 10000; i++ {
+		ps.Publish("ui", i)
+	}
+	ps.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reader did not finish after Shutdown")
+	}
+}
+
+func TestSubscribeLatestAppliesDeliveryMiddleware(t *testing.T) {
+	ps := NewPubSub[int]()
+	ps.UseDelivery(func(next DeliverFunc[int]) DeliverFunc[int] {
+		return func(topic string, msg int) error {
+			return next(topic, msg*10)
+		}
+	})
+	ch := ps.SubscribeLatest("ui")
+
+	ps.Publish("ui", 1)
+	ps.Publish("ui", 2)
+	if got := <-ch; got != 20 {
+		t.Errorf("Expected the middleware to see the newest value, got %d", got)
+	}
+}
@@ -0,0 +1,72 @@
+package pubsub
+
+// PublishFunc publishes a message on a topic.
+type PublishFunc[T any] func(topic string, message T) error
+
+// PublishMiddleware wraps the publish path. A middleware may modify the message or topic,
+// reject it by returning an error without calling next, or fan it out by calling next several times.
+type PublishMiddleware[T any] func(next PublishFunc[T]) PublishFunc[T]
+
+// DeliverFunc delivers a message to a single subscriber.
+// The innermost DeliverFunc enqueues the message for the subscriber's dispatcher.
+type DeliverFunc[T any] func(topic string, message T) error
+
+// DeliveryMiddleware wraps the delivery of a message to each subscriber, including
+// subscribers created by Handle and RequestReply. A middleware may modify the message,
+// skip the subscriber by returning without calling next, or deliver several messages.
+// Deliveries run on the publishing goroutine.
+type DeliveryMiddleware[T any] func(next DeliverFunc[T]) DeliverFunc[T]
+
+// Use appends publish middleware. Middleware run in the order they were added,
+// the first one being the outermost.
+func (ps *PubSub[T]) Use(middleware ...PublishMiddleware[T]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.publishMiddleware = append(ps.publishMiddleware, middleware...)
+
+	// Rebuild the chain from the innermost handler outwards
+	chain := PublishFunc[T](ps.publish)
+	for i := len(ps.publishMiddleware) - 1; i >= 0; i-- {
+		chain = ps.publishMiddleware[i](chain)
+	}
+	ps.publishChain.Store(&chain)
+}
+
+// UseDelivery appends delivery middleware. Middleware run in the order they were added,
+// the first one being the outermost. Existing subscribers pick up the new chain immediately.
+func (ps *PubSub[T]) UseDelivery(middleware ...DeliveryMiddleware[T]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	current := *ps.deliveryMiddleware.Load()
+	next := make([]DeliveryMiddleware[T], 0, len(current)+len(middleware))
+	next = append(append(next, current...), middleware...)
+	ps.deliveryMiddleware.Store(&next)
+
+	// Rebuild the delivery chain of every current subscriber. Holding each shard's lock
+	// ensures a concurrent Subscribe either sees the new middleware or is rebuilt here.
+	for i := range ps.shards {
+		s := &ps.shards[i]
+		s.mu.Lock()
+		for _, t := range *s.topics.Load() {
+			for _, sub := range *t.subscribers.Load() {
+				sub.setDeliver(ps.deliveryChain(sub))
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// deliveryChain composes the delivery middleware around enqueueing to sub.
+// Must be called with the lock of the shard owning sub held.
+func (ps *PubSub[T]) deliveryChain(sub *subscriber[T]) DeliverFunc[T] {
+	chain := DeliverFunc[T](func(topic string, message T) error {
+		return sub.enqueue(message)
+	})
+	middleware := *ps.deliveryMiddleware.Load()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		chain = middleware[i](chain)
+	}
+	return chain
+}
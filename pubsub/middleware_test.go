@@ -0,0 +1,138 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPublishMiddleware(t *testing.T) {
+	ps := NewPubSub[string]()
+
+	var order []string
+	var mu sync.Mutex
+	trace := func(name string) PublishMiddleware[string] {
+		return func(next PublishFunc[string]) PublishFunc[string] {
+			return func(topic, message string) error {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return next(topic, message)
+			}
+		}
+	}
+
+	errUnauthorized := errors.New("unauthorized")
+	ps.Use(
+		trace("first"),
+		trace("second"),
+		// Reject messages on restricted topics
+		func(next PublishFunc[string]) PublishFunc[string] {
+			return func(topic, message string) error {
+				if strings.HasPrefix(topic, "admin.") {
+					return errUnauthorized
+				}
+				return next(topic, message)
+			}
+		},
+		// Enrich messages
+		func(next PublishFunc[string]) PublishFunc[string] {
+			return func(topic, message string) error {
+				return next(topic, "["+topic+"] "+message)
+			}
+		},
+		// Fan out news to an archive topic
+		func(next PublishFunc[string]) PublishFunc[string] {
+			return func(topic, message string) error {
+				if topic == "news" {
+					if err := next("archive", message); err != nil {
+						return err
+					}
+				}
+				return next(topic, message)
+			}
+		},
+	)
+
+	news := ps.Subscribe("news")
+	archive := ps.Subscribe("archive")
+	admin := ps.Subscribe("admin.users")
+
+	if err := ps.Publish("news", "hello"); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := ps.Publish("admin.users", "drop table"); !errors.Is(err, errUnauthorized) {
+		t.Errorf("Expected rejection, got %v", err)
+	}
+
+	if got := <-news; got != "[news] hello" {
+		t.Errorf("Unexpected news message: %q", got)
+	}
+	if got := <-archive; got != "[news] hello" {
+		t.Errorf("Unexpected archive message: %q", got)
+	}
+	if len(admin) != 0 {
+		t.Errorf("Rejected message was delivered")
+	}
+	if strings.Join(order, ",") != "first,second,first,second" {
+		t.Errorf("Middleware ran out of order: %v", order)
+	}
+	ps.Shutdown()
+}
+
+func TestDeliveryMiddleware(t *testing.T) {
+	ps := NewPubSub[string]()
+
+	// Subscribers created before and after UseDelivery, including a Handle worker pool
+	early := ps.Subscribe("events")
+	var handled []string
+	var mu sync.Mutex
+	h := ps.Handle("events", func(ctx context.Context, msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, msg)
+		return nil
+	})
+
+	ps.UseDelivery(
+		// Skip messages marked private
+		func(next DeliverFunc[string]) DeliverFunc[string] {
+			return func(topic, message string) error {
+				if strings.HasPrefix(message, "private:") {
+					return nil
+				}
+				return next(topic, message)
+			}
+		},
+		// Modify messages
+		func(next DeliverFunc[string]) DeliverFunc[string] {
+			return func(topic, message string) error {
+				return next(topic, strings.ToUpper(message))
+			}
+		},
+	)
+	late := ps.Subscribe("events")
+
+	ps.Publish("events", "private: secret")
+	ps.Publish("events", "hello")
+	h.Stop()
+	ps.Shutdown() // Closes the remaining channels once pending messages are flushed
+
+	for name, ch := range map[string]chan string{"early": early, "late": late} {
+		var got []string
+		for msg := range ch {
+			got = append(got, msg)
+		}
+		if len(got) != 1 || got[0] != "HELLO" {
+			t.Errorf("%s subscriber: received %v, expected [HELLO]", name, got)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 1 || handled[0] != "HELLO" {
+		t.Errorf("Handler received %v, expected [HELLO]", handled)
+	}
+}
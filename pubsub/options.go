@@ -0,0 +1,22 @@
+package pubsub
+
+import "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
+
+// Option configures a PubSub created by NewPubSub.
+type Option func(*options)
+
+// options holds the settings collected from Options
+type options struct {
+	shards         int            // Number of independently locked topic buckets
+	clock          clock.Clock    // Source of time for time-based features
+	inFlightLimit  int64          // Maximum weight of outstanding deliveries; 0 means unlimited
+	inFlightPolicy InFlightPolicy // What happens to deliveries over the in-flight limit
+}
+
+// WithClock sets the clock used for time measurements, such as handler latency.
+// Tests pass a fake clock to control time deterministically.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
@@ -1,105 +1,173 @@
 package pubsub
 
 import (
-	"fmt"
+	"errors"
 	"sync"
+	"sync/atomic"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
 )
 
+// ErrDropped is returned to delivery middleware when a subscriber's queue is full.
+var ErrDropped = errors.New("pubsub: subscriber buffer full, message dropped")
+
+// ErrClosed is returned when a subscription has been closed by Unsubscribe or Shutdown.
+var ErrClosed = errors.New("pubsub: subscription closed")
+
 // PubSub manages publishers and subscribers for any message type
 // T is a generic type that allows PubSub to handle heterogeneous data types.
+//
+// Topics are spread across independently locked shards, and each shard's registry is
+// copy-on-write: Publish reads immutable snapshots through atomic pointers and takes no
+// locks, while Subscribe and Unsubscribe build new snapshots under their shard's lock.
 type PubSub[T any] struct {
-	subscribers map[string]map[chan T]struct{} // Map of topics to a set of subscriber channels
-	mu          sync.RWMutex                   // Read-Write lock to manage concurrent access
+	shards   []shard[T]  // Topic buckets, selected by a hash of the topic name
+	clock    clock.Clock // Source of time for time-based features
+	inFlight *inFlight   // Caps outstanding deliveries; nil if WithInFlightLimit was not given
+
+	mu                 sync.Mutex                              // Serializes middleware registration
+	publishMiddleware  []PublishMiddleware[T]                  // Applied around every Publish, in registration order
+	deliveryMiddleware atomic.Pointer[[]DeliveryMiddleware[T]] // Applied around every delivery to a subscriber
+	publishChain       atomic.Pointer[PublishFunc[T]]          // Publish middleware composed around ps.publish
+}
+
+// topic holds the current immutable snapshot of a topic's subscribers
+type topic[T any] struct {
+	subscribers atomic.Pointer[[]*subscriber[T]] // Never modified in place; replaced on every change
 }
 
 // NewPubSub initializes a new PubSub instance for a specific type.
 // This is a generic constructor that creates the internal data structures.
-func NewPubSub[T any]() *PubSub[T] {
-	return &PubSub[T]{
-		subscribers: make(map[string]map[chan T]struct{}), // Initialize the subscriber map
+func NewPubSub[T any](opts ...Option) *PubSub[T] {
+	o := options{shards: DefaultShards, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ps := &PubSub[T]{
+		shards: make([]shard[T], o.shards),
+		clock:  o.clock,
+	}
+	if o.inFlightLimit > 0 {
+		ps.inFlight = newInFlight(o.inFlightLimit, o.inFlightPolicy, o.clock)
 	}
+	for i := range ps.shards {
+		ps.shards[i].topics.Store(&map[string]*topic[T]{}) // Initialize each shard's topic map
+	}
+	ps.deliveryMiddleware.Store(&[]DeliveryMiddleware[T]{})
+	publish := PublishFunc[T](ps.publish)
+	ps.publishChain.Store(&publish)
+	return ps
 }
 
 // Subscribe adds a new subscriber to a specific topic.
 // Returns a channel through which the subscriber will receive messages.
+// Each subscription owns a dispatcher goroutine that feeds the channel from a queue.
 func (ps *PubSub[T]) Subscribe(topic string) chan T {
 	// Create a buffered channel to prevent blocking during message delivery
 	ch := make(chan T, 100) // Buffered channel size is set to 100 for high throughput
-	ps.mu.Lock()
-	defer ps.mu.Unlock()
+	ps.add(topic, newSubscriber(ch, ps.inFlight))
+	return ch
+}
 
-	// Initialize the topic in the map if it doesn't exist
-	if ps.subscribers[topic] == nil {
-		ps.subscribers[topic] = make(map[chan T]struct{})
-	}
+// add registers a subscriber with a topic
+func (ps *PubSub[T]) add(topic string, sub *subscriber[T]) {
+	s := ps.shardFor(topic)
+	s.mu.Lock() // Only writers on the same shard are blocked
+	defer s.mu.Unlock()
 
-	// Add the subscriber channel to the topic
-	ps.subscribers[topic][ch] = struct{}{}
-	return ch
+	sub.setDeliver(ps.deliveryChain(sub))
+
+	// Publish a new snapshot containing the subscriber
+	t := s.getOrCreateTopic(topic)
+	current := *t.subscribers.Load()
+	next := make([]*subscriber[T], len(current), len(current)+1)
+	copy(next, current)
+	next = append(next, sub)
+	t.subscribers.Store(&next)
 }
 
 // Publish sends a message to all subscribers of a given topic.
-// The message is delivered concurrently to ensure high throughput.
-func (ps *PubSub[T]) Publish(topic string, message T) {
-	ps.mu.RLock() // Acquire read lock to allow concurrent publishing
-	defer ps.mu.RUnlock()
-
-	var wg sync.WaitGroup // WaitGroup to ensure all goroutines finish
-
-	// Iterate over all subscriber channels for the topic
-	for ch := range ps.subscribers[topic] {
-		wg.Add(1)
-		// Deliver message to each subscriber in a separate goroutine
-		go func(c chan T) {
-			defer wg.Done()
-			// Send the message or drop it if the channel is full
-			select {
-			case c <- message:
-				// Message successfully delivered
-			default:
-				// Channel is full; drop the message to avoid blocking
-				fmt.Println("Subscriber is too slow. Dropping message.")
-			}
-		}(ch)
+// The message first passes through the publish middleware chain; an error
+// returned by a middleware (e.g. a rejected message) is returned to the caller.
+func (ps *PubSub[T]) Publish(topic string, message T) error {
+	return (*ps.publishChain.Load())(topic, message)
+}
+
+// publish is the innermost PublishFunc: it fans the message out to the topic's subscribers.
+// Delivery only enqueues the message; each subscriber's dispatcher sends it on the channel,
+// so Publish never blocks on a consumer, spawns no goroutines and takes no broker locks.
+func (ps *PubSub[T]) publish(topic string, message T) error {
+	t, ok := ps.shardFor(topic).lookup(topic)
+	if !ok {
+		return nil
 	}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+	// Iterate over the subscriber snapshot; a subscriber removed meanwhile rejects the message
+	for _, sub := range *t.subscribers.Load() {
+		_ = sub.deliverFunc()(topic, message) // Drops and rejections are handled by the chain
+	}
+	return nil
 }
 
 // Unsubscribe removes a subscriber from a specific topic.
 // The channel is closed to signal the subscriber that no more messages will be sent.
 func (ps *PubSub[T]) Unsubscribe(topic string, ch chan T) {
-	ps.mu.Lock() // Acquire write lock to modify the subscriber map
-	defer ps.mu.Unlock()
+	s := ps.shardFor(topic)
+	s.mu.Lock() // Serialize with other writers on the shard
 
+	var removed *subscriber[T]
 	// Check if the topic exists
-	if subscribers, ok := ps.subscribers[topic]; ok {
-		// Remove the subscriber channel if it exists
-		if _, exists := subscribers[ch]; exists {
-			delete(subscribers, ch)
-			close(ch) // Close the channel to clean up resources
+	if t, ok := s.lookup(topic); ok {
+		current := *t.subscribers.Load()
+		next := make([]*subscriber[T], 0, len(current))
+		for _, sub := range current {
+			if sub.ch == ch {
+				removed = sub
+				continue
+			}
+			next = append(next, sub)
+		}
+
+		if removed != nil {
+			t.subscribers.Store(&next)
+			removed.close() // Stop the dispatcher; it closes the channel
 		}
 		// If no subscribers remain for the topic, remove the topic
-		if len(subscribers) == 0 {
-			delete(ps.subscribers, topic)
+		if len(next) == 0 {
+			s.removeTopic(topic)
 		}
 	}
+	s.mu.Unlock()
+
+	// Wait outside the lock until the channel is closed
+	if removed != nil {
+		removed.wait()
+	}
 }
 
 // Shutdown gracefully shuts down the PubSub system by closing all channels.
 // This signals all subscribers that no more messages will be sent.
 func (ps *PubSub[T]) Shutdown() {
-	ps.mu.Lock() // Acquire write lock to prevent new subscriptions/publishing
-	defer ps.mu.Unlock()
-
-	// Iterate over all topics
-	for topic, subscribers := range ps.subscribers {
-		// Close all channels for each topic
-		for ch := range subscribers {
-			close(ch)
+	var removed []*subscriber[T]
+	for i := range ps.shards {
+		s := &ps.shards[i]
+		s.mu.Lock()
+
+		// Swap in an empty registry so new publishes find no subscribers
+		old := s.topics.Swap(&map[string]*topic[T]{})
+		for _, t := range *old {
+			// Stop all dispatchers for each topic
+			for _, sub := range *t.subscribers.Load() {
+				sub.close()
+				removed = append(removed, sub)
+			}
 		}
-		// Remove the topic from the map
-		delete(ps.subscribers, topic)
+		s.mu.Unlock()
+	}
+
+	// Wait until every channel is closed
+	for _, sub := range removed {
+		sub.wait()
 	}
 }
@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// legacyPubSub reproduces the previous goroutine-per-subscriber-per-message fan-out
+// so the dispatcher design can be compared against it.
+type legacyPubSub[T any] struct {
+	subscribers map[string]map[chan T]struct{}
+	mu          sync.RWMutex
+}
+
+func (ps *legacyPubSub[T]) Subscribe(topic string) chan T {
+	ch := make(chan T, 100)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.subscribers[topic] == nil {
+		ps.subscribers[topic] = make(map[chan T]struct{})
+	}
+	ps.subscribers[topic][ch] = struct{}{}
+	return ch
+}
+
+func (ps *legacyPubSub[T]) Publish(topic string, message T) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	var wg sync.WaitGroup
+	for ch := range ps.subscribers[topic] {
+		wg.Add(1)
+		go func(c chan T) {
+			defer wg.Done()
+			select {
+			case c <- message:
+			default:
+			}
+		}(ch)
+	}
+	wg.Wait()
+}
+
+func (ps *legacyPubSub[T]) Shutdown() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for topic, subscribers := range ps.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(ps.subscribers, topic)
+	}
+}
+
+// broker is the subset of the API exercised by the fan-out benchmarks
+type broker interface {
+	Subscribe(topic string) chan time.Time
+	Publish(topic string, message time.Time)
+	Shutdown()
+}
+
+// dispatcherBroker adapts PubSub to the broker interface
+type dispatcherBroker struct{ *PubSub[time.Time] }
+
+func (d dispatcherBroker) Publish(topic string, message time.Time) {
+	_ = d.PubSub.Publish(topic, message)
+}
+
+// benchmarkFanOut publishes timestamps to numSubscribers consumers and reports
+// the mean publish-to-receive latency alongside ns/op and allocations.
+func benchmarkFanOut(b *testing.B, ps broker, numSubscribers int) {
+	var received, totalLatency atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < numSubscribers; i++ {
+		ch := ps.Subscribe("bench")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sent := range ch {
+				totalLatency.Add(int64(time.Since(sent)))
+				received.Add(1)
+			}
+		}()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish("bench", time.Now())
+	}
+	b.StopTimer()
+
+	ps.Shutdown()
+	wg.Wait()
+
+	if n := received.Load(); n > 0 {
+		b.ReportMetric(float64(totalLatency.Load())/float64(n), "ns/delivery")
+		b.ReportMetric(float64(n)/float64(b.N*numSubscribers), "delivered")
+	}
+}
+
+func BenchmarkFanOut10kSubscribers(b *testing.B) {
+	const numSubscribers = 10000
+
+	b.Run("dispatcher", func(b *testing.B) {
+		benchmarkFanOut(b, dispatcherBroker{NewPubSub[time.Time]()}, numSubscribers)
+	})
+	b.Run("goroutine-per-message", func(b *testing.B) {
+		benchmarkFanOut(b, &legacyPubSub[time.Time]{subscribers: make(map[string]map[chan time.Time]struct{})}, numSubscribers)
+	})
+}
+
+// benchmarkFanOutLatency publishes one message at a time and waits until every subscriber
+// has received it, so ns/op is the end-to-end latency of a single fan-out.
+func benchmarkFanOutLatency(b *testing.B, ps broker, numSubscribers int) {
+	var pending atomic.Int64
+	delivered := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < numSubscribers; i++ {
+		ch := ps.Subscribe("bench")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ch {
+				if pending.Add(-1) == 0 {
+					delivered <- struct{}{}
+				}
+			}
+		}()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pending.Store(int64(numSubscribers))
+		ps.Publish("bench", time.Now())
+		<-delivered
+	}
+	b.StopTimer()
+
+	ps.Shutdown()
+	wg.Wait()
+}
+
+func BenchmarkFanOutLatency10kSubscribers(b *testing.B) {
+	const numSubscribers = 10000
+
+	b.Run("dispatcher", func(b *testing.B) {
+		benchmarkFanOutLatency(b, dispatcherBroker{NewPubSub[time.Time]()}, numSubscribers)
+	})
+	b.Run("goroutine-per-message", func(b *testing.B) {
+		benchmarkFanOutLatency(b, &legacyPubSub[time.Time]{subscribers: make(map[string]map[chan time.Time]struct{})}, numSubscribers)
+	})
+}
@@ -0,0 +1,117 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// AdaptiveConfig configures additive-increase/multiplicative-decrease (AIMD) control of the global limit.
+// Every Interval the limit grows by Increase while subscribers keep up, and is multiplied by Decrease
+// when a subscriber dropped a message or a subscriber buffer is at least HighWatermark full.
+// Zero fields take the defaults noted below. An infinite limit has no rate to be multiplied, so the first
+// decrease from it is applied to the rate at which messages were published during the interval.
+type AdaptiveConfig struct {
+	Min           rate.Limit    // Lower bound of the limit
+	Max           rate.Limit    // Upper bound of the limit. Defaults to the limit passed to NewPubSub, which may be rate.Inf
+	Increase      rate.Limit    // Added per healthy interval. Defaults to 1 message/s
+	Decrease      float64       // Factor applied per congested interval, between 0 and 1. Defaults to 0.5
+	HighWatermark float64       // Buffer occupancy, between 0 and 1, treated as congestion. Defaults to 0.8
+	Interval      time.Duration // How often the limit is adjusted. Defaults to 1s
+}
+
+// adaptive holds the AIMD controller state
+type adaptive struct {
+	cfg AdaptiveConfig
+
+	mu        sync.Mutex
+	next      time.Time // When the limit is adjusted next
+	lastDrops uint64    // Value of PubSub.dropped at the last adjustment
+	published int       // Publishes since the last adjustment
+}
+
+func newAdaptive(cfg AdaptiveConfig, initial rate.Limit, now time.Time) *adaptive {
+	if cfg.Max == 0 {
+		cfg.Max = initial
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Increase <= 0 {
+		cfg.Increase = 1
+	}
+	if cfg.Decrease <= 0 || cfg.Decrease >= 1 {
+		cfg.Decrease = 0.5
+	}
+	if cfg.HighWatermark <= 0 || cfg.HighWatermark > 1 {
+		cfg.HighWatermark = 0.8
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+
+	return &adaptive{cfg: cfg, next: now.Add(cfg.Interval)}
+}
+
+// adapt adjusts the global limit once per interval. It runs on the publish path,
+// so no background goroutine is needed and the limit only moves while messages are published.
+// It starts from the limit in effect, so a SetLimit call is picked up as the new operating point.
+// The new limit is applied with changeLimits, so publishers waiting in ModeWait see it too.
+func (ps *PubSub[T]) adapt(now time.Time) {
+	a := ps.adaptive
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Before(a.next) {
+		a.published++
+		return
+	}
+	interval := a.cfg.Interval + now.Sub(a.next) // Time since the last adjustment
+	published := a.published
+	a.next, a.published = now.Add(a.cfg.Interval), 1 // This publish starts the next interval
+
+	drops := ps.dropped.Load()
+	congested := drops != a.lastDrops || ps.occupancy() >= a.cfg.HighWatermark
+	a.lastDrops = drops
+
+	current := ps.limiter.Limit()
+	r := current
+	if congested {
+		if r == rate.Inf {
+			r = rate.Limit(float64(published) / interval.Seconds()) // Back off from the rate actually published
+		}
+		r *= rate.Limit(a.cfg.Decrease)
+	} else {
+		r += a.cfg.Increase
+	}
+	if r = clampRate(r, a.cfg.Min, a.cfg.Max); r != current {
+		ps.changeLimits(func(now time.Time) {
+			ps.limiter.SetLimitAt(now, r)
+		})
+	}
+}
+
+// occupancy returns the fill ratio of the fullest subscriber buffer
+func (ps *PubSub[T]) occupancy() float64 {
+	var fullest float64
+	for _, t := range *ps.topics.Load() {
+		for _, sub := range *t.subscribers.Load() {
+			if c := cap(sub.ch); c > 0 {
+				if f := float64(len(sub.ch)) / float64(c); f > fullest {
+					fullest = f
+				}
+			}
+		}
+	}
+	return fullest
+}
+
+func clampRate(r, lo, hi rate.Limit) rate.Limit {
+	if r < lo {
+		return lo
+	}
+	if r > hi {
+		return hi
+	}
+	return r
+}
@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestAdaptiveIncreasesWhileHealthyAndBacksOffOnCongestion(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](50, 1000, WithClock(clk), WithAdaptive(AdaptiveConfig{
+		Min:      10,
+		Max:      80,
+		Increase: 10,
+		Decrease: 0.5,
+		Interval: time.Second,
+	}))
+	sub := ps.Subscribe("topic")
+
+	// The subscriber keeps up: +10 per interval up to Max
+	var got []rate.Limit
+	for i := 0; i < 4; i++ {
+		clk.Advance(time.Second)
+		ps.Publish("topic", i)
+		<-sub
+		got = append(got, ps.Limit())
+	}
+	assertLimits(t, "healthy", got, []rate.Limit{60, 70, 80, 80})
+
+	// The subscriber stops reading: once its buffer is 80% full the limit halves, down to Min
+	for i := 0; i < 80; i++ {
+		ps.Publish("topic", i)
+	}
+	got = got[:0]
+	for i := 0; i < 4; i++ {
+		clk.Advance(time.Second)
+		ps.Publish("topic", i)
+		got = append(got, ps.Limit())
+	}
+	assertLimits(t, "congested", got, []rate.Limit{40, 20, 10, 10})
+}
+
+func TestAdaptiveBacksOffOnDrops(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](100, 1000, WithClock(clk), WithAdaptive(AdaptiveConfig{
+		Min:           1,
+		HighWatermark: 1, // Only drops count as congestion
+	}))
+	sub := ps.Subscribe("topic")
+
+	// Overflow the 100-message buffer by one
+	for i := 0; i < 101; i++ {
+		ps.Publish("topic", i)
+	}
+	if ps.Dropped() != 1 {
+		t.Fatalf("Expected 1 dropped message, got %d", ps.Dropped())
+	}
+	for len(sub) > 0 {
+		<-sub
+	}
+
+	clk.Advance(time.Second)
+	ps.Publish("topic", 0)
+	if ps.Limit() != 50 {
+		t.Errorf("Expected the limit to halve after a drop, got %v", ps.Limit())
+	}
+
+	// No new drops: the limit grows again by the default step of 1
+	clk.Advance(time.Second)
+	ps.Publish("topic", 0)
+	if ps.Limit() != 51 {
+		t.Errorf("Expected the limit to grow to 51, got %v", ps.Limit())
+	}
+}
+
+func TestAdaptiveBacksOffFromAnInfiniteLimit(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 1, WithClock(clk), WithAdaptive(AdaptiveConfig{}))
+	ps.Subscribe("topic") // Never read, so its buffer fills up
+
+	// 100 messages in the first second fill the buffer; the next publish adjusts the limit
+	for i := 0; i < 100; i++ {
+		ps.Publish("topic", i)
+	}
+	clk.Advance(time.Second)
+	ps.Publish("topic", 100)
+	if ps.Limit() != 50 {
+		t.Errorf("Expected the limit to halve the 100 messages/s published, got %v", ps.Limit())
+	}
+}
+
+func TestAdaptiveWakesWaitingPublishers(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](0.1, 1, WithClock(clk), WithMode(ModeWait), WithAdaptive(AdaptiveConfig{
+		Max:      10,
+		Increase: 10,
+		Interval: time.Second,
+	}))
+	sub := ps.Subscribe("topic")
+	ps.Publish("topic", 0) // Uses the only token; the next is due in 10s
+
+	done := make(chan error, 1)
+	go func() { done <- ps.Publish("topic", 1) }()
+	clk.BlockUntil(1)
+
+	// Another publish after the interval raises the limit to 10 messages/s
+	clk.Advance(time.Second)
+	go ps.PublishReserve("topic", 2)
+	for i := 0; ; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+		case <-time.After(10 * time.Millisecond):
+			if i < 5 {
+				clk.Advance(100 * time.Millisecond)
+				continue
+			}
+			t.Fatalf("Expected the waiting publisher to see the raised limit, limit is %v", ps.Limit())
+		}
+		break
+	}
+	if msg := <-sub; msg != 0 {
+		t.Errorf("Expected message 0 first, got %d", msg)
+	}
+}
+
+func assertLimits(t *testing.T, phase string, got, want []rate.Limit) {
+	t.Helper()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: expected limits %v, got %v", phase, want, got)
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// LeakyBucket queues events in a bucket of burst slots that drains at exactly r per second,
+// so events are spaced evenly and never pass in bursts. An event that finds the bucket full
+// cannot be reserved at all, and is rejected even in ModeWait. A zero rate lets nothing through.
+func LeakyBucket(r rate.Limit, burst int) Limiter {
+	return &leakyBucket{limit: r, burst: burst}
+}
+
+type leakyBucket struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	next  time.Time // When the bucket drains the next slot
+}
+
+func (l *leakyBucket) ReserveN(now time.Time, n int) Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf {
+		return allowed(now)
+	}
+	if l.limit <= 0 {
+		return &reservation{}
+	}
+
+	step := interval(l.limit)
+	t := now
+	if l.next.After(t) {
+		t = l.next
+	}
+	queued := int((t.Sub(now) + step - 1) / step) // Events still waiting to drain
+	if queued+n > l.burst {
+		return &reservation{}
+	}
+
+	end := t.Add(time.Duration(n) * step)
+	l.next = end
+	return &reservation{ok: true, timeToAct: t, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.next.Equal(end) {
+			l.next = t // Only the latest reservation can be taken back out of the queue
+		}
+	}}
+}
+
+func (l *leakyBucket) TokensAt(now time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf || l.limit <= 0 || !l.next.After(now) {
+		return float64(l.burst)
+	}
+	step := interval(l.limit)
+	queued := int((l.next.Sub(now) + step - 1) / step)
+	return float64(l.burst - queued)
+}
+
+func (l *leakyBucket) Limit() rate.Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *leakyBucket) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+func (l *leakyBucket) SetLimitAt(now time.Time, r rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = r
+}
+
+func (l *leakyBucket) SetBurstAt(now time.Time, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = burst
+}
+
+// GCRA is the generic cell rate algorithm: it tracks a single theoretical arrival time and allows
+// an event if that time is no more than burst intervals ahead of now. It behaves like a token bucket
+// but keeps its state in integer nanoseconds, so results never depend on floating-point rounding.
+// A zero rate lets nothing through.
+func GCRA(r rate.Limit, burst int) Limiter {
+	return &gcra{limit: r, burst: burst}
+}
+
+type gcra struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	tat   time.Time // Theoretical arrival time of the next event
+}
+
+func (l *gcra) ReserveN(now time.Time, n int) Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf {
+		return allowed(now)
+	}
+	if l.limit <= 0 || n > l.burst {
+		return &reservation{}
+	}
+
+	step := interval(l.limit)
+	tat := l.tat
+	if now.After(tat) {
+		tat = now
+	}
+	next := tat.Add(time.Duration(n) * step)
+	t := now
+	if allowAt := next.Add(-time.Duration(l.burst) * step); allowAt.After(t) {
+		t = allowAt
+	}
+
+	l.tat = next
+	return &reservation{ok: true, timeToAct: t, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.tat.Equal(next) {
+			l.tat = tat // Only the latest reservation can be given back
+		}
+	}}
+}
+
+func (l *gcra) TokensAt(now time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf || l.limit <= 0 || !l.tat.After(now) {
+		return float64(l.burst)
+	}
+	return float64(l.burst) - float64(l.tat.Sub(now))/float64(interval(l.limit))
+}
+
+func (l *gcra) Limit() rate.Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *gcra) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+func (l *gcra) SetLimitAt(now time.Time, r rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = r
+}
+
+func (l *gcra) SetBurstAt(now time.Time, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = burst
+}
@@ -0,0 +1,224 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SetLimit changes the global rate limit. It takes effect immediately, including for publishers
+// already waiting in ModeWait, which reserve again under the new limit.
+func (ps *PubSub[T]) SetLimit(r rate.Limit) {
+	ps.changeLimits(func(now time.Time) {
+		ps.limiter.SetLimitAt(now, r)
+	})
+}
+
+// SetBurst changes the global burst size. Like SetLimit, it applies to publishers already waiting.
+func (ps *PubSub[T]) SetBurst(burst int) {
+	ps.changeLimits(func(now time.Time) {
+		ps.limiter.SetBurstAt(now, burst)
+	})
+}
+
+// Limit returns the global rate limit.
+func (ps *PubSub[T]) Limit() rate.Limit {
+	return ps.limiter.Limit()
+}
+
+// Burst returns the global burst size.
+func (ps *PubSub[T]) Burst() int {
+	return ps.limiter.Burst()
+}
+
+// SetTopicLimit changes the limit of one topic, replacing any WithTopicLimit for it.
+// The rate and burst are applied together.
+func (ps *PubSub[T]) SetTopicLimit(topic string, r rate.Limit, burst int) {
+	ps.changeLimits(func(now time.Time) {
+		ps.topicLimiters.set(topic, limit{rate: r, burst: burst}, now)
+	})
+}
+
+// ClearTopicLimit removes the explicit limit of a topic so that the default topic limit applies again.
+func (ps *PubSub[T]) ClearTopicLimit(topic string) {
+	ps.changeLimits(func(now time.Time) {
+		ps.topicLimiters.clear(topic, now)
+	})
+}
+
+// TopicLimit returns the rate and burst that apply to a topic.
+func (ps *PubSub[T]) TopicLimit(topic string) (rate.Limit, int) {
+	lim := ps.topicLimiters.limitOf(topic)
+	return lim.rate, lim.burst
+}
+
+// SetPublisherLimit changes the limit of one publisher identity, replacing any WithPublisherLimit for it.
+func (ps *PubSub[T]) SetPublisherLimit(publisher string, r rate.Limit, burst int) {
+	ps.changeLimits(func(now time.Time) {
+		ps.publisherLimiters.set(publisher, limit{rate: r, burst: burst}, now)
+	})
+}
+
+// ClearPublisherLimit removes the explicit limit of a publisher so that the default publisher limit applies again.
+func (ps *PubSub[T]) ClearPublisherLimit(publisher string) {
+	ps.changeLimits(func(now time.Time) {
+		ps.publisherLimiters.clear(publisher, now)
+	})
+}
+
+// PublisherLimit returns the rate and burst that apply to a publisher identity.
+func (ps *PubSub[T]) PublisherLimit(publisher string) (rate.Limit, int) {
+	lim := ps.publisherLimiters.limitOf(publisher)
+	return lim.rate, lim.burst
+}
+
+// Config is the JSON format read by WatchConfig. A negative limit means no limit.
+//
+//	{
+//	  "limit": 1000, "burst": 100,
+//	  "topics": {"metrics": {"limit": 50, "burst": 10}},
+//	  "publishers": {"batch-job": {"limit": 5, "burst": 1}}
+//	}
+type Config struct {
+	Limit      *float64               `json:"limit,omitempty"` // Global limit; unchanged if absent
+	Burst      *int                   `json:"burst,omitempty"` // Global burst; unchanged if absent
+	Topics     map[string]LimitConfig `json:"topics,omitempty"`
+	Publishers map[string]LimitConfig `json:"publishers,omitempty"`
+}
+
+// LimitConfig is the limit of one topic or publisher in a Config
+type LimitConfig struct {
+	Limit float64 `json:"limit"`
+	Burst int     `json:"burst"`
+}
+
+// toRate converts a configured limit, where negative means unlimited
+func toRate(limit float64) rate.Limit {
+	if limit < 0 {
+		return rate.Inf
+	}
+	return rate.Limit(limit)
+}
+
+// ApplyConfig applies every limit in cfg at once: no publish sees some of the new limits and not others.
+// Topics and publishers that are not listed keep their current limits.
+func (ps *PubSub[T]) ApplyConfig(cfg Config) {
+	ps.changeLimits(func(now time.Time) {
+		ps.applyConfigAt(now, cfg)
+	})
+}
+
+// applyConfigAt applies cfg. Must be called from a changeLimits function.
+func (ps *PubSub[T]) applyConfigAt(now time.Time, cfg Config) {
+	if cfg.Limit != nil {
+		ps.limiter.SetLimitAt(now, toRate(*cfg.Limit))
+	}
+	if cfg.Burst != nil {
+		ps.limiter.SetBurstAt(now, *cfg.Burst)
+	}
+	for topic, lim := range cfg.Topics {
+		ps.topicLimiters.set(topic, limit{rate: toRate(lim.Limit), burst: lim.Burst}, now)
+	}
+	for publisher, lim := range cfg.Publishers {
+		ps.publisherLimiters.set(publisher, limit{rate: toRate(lim.Limit), burst: lim.Burst}, now)
+	}
+}
+
+// changeLimits runs change while no reservation is being made, then wakes the publishers waiting
+// in ModeWait so that they reserve again under the new limits.
+func (ps *PubSub[T]) changeLimits(change func(now time.Time)) {
+	ps.limitsMu.Lock()
+	defer ps.limitsMu.Unlock()
+	change(ps.clock.Now())
+	close(ps.limitsChanged)
+	ps.limitsChanged = make(chan struct{})
+}
+
+// limitsChangedChan returns a channel that is closed the next time the limits change
+func (ps *PubSub[T]) limitsChangedChan() <-chan struct{} {
+	ps.limitsMu.RLock()
+	defer ps.limitsMu.RUnlock()
+	return ps.limitsChanged
+}
+
+// WatchConfig applies the Config in the JSON file at path, then polls the file every interval
+// and applies it again whenever its contents change. Topics and publishers removed from the file
+// fall back to the default limits. The first read must succeed; later read or parse errors are
+// passed to onError, if not nil, and the previous limits stay in effect.
+// Call the returned stop function to stop watching.
+func (ps *PubSub[T]) WatchConfig(path string, interval time.Duration, onError func(error)) (stop func(), err error) {
+	w := &configWatcher[T]{ps: ps, path: path}
+	if err := w.reload(); err != nil {
+		return nil, err
+	}
+
+	ticker := ps.clock.NewTicker(interval)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ticker.C():
+				if err := w.reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(quit)
+		<-done
+	}, nil
+}
+
+// configWatcher remembers the last applied file so changes can be detected and removals undone
+type configWatcher[T any] struct {
+	ps      *PubSub[T]
+	path    string
+	data    []byte // Contents of the last applied file
+	applied Config
+}
+
+// reload reads the file and applies it if it changed
+func (w *configWatcher[T]) reload() error {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return fmt.Errorf("ratelimiter: read config: %w", err)
+	}
+	if w.data != nil && bytes.Equal(data, w.data) {
+		return nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("ratelimiter: parse config %s: %w", w.path, err)
+	}
+
+	// Undo limits that are no longer in the file and apply the new ones in a single change
+	w.ps.changeLimits(func(now time.Time) {
+		for topic := range w.applied.Topics {
+			if _, ok := cfg.Topics[topic]; !ok {
+				w.ps.topicLimiters.clear(topic, now)
+			}
+		}
+		for publisher := range w.applied.Publishers {
+			if _, ok := cfg.Publishers[publisher]; !ok {
+				w.ps.publisherLimiters.clear(publisher, now)
+			}
+		}
+		w.ps.applyConfigAt(now, cfg)
+	})
+
+	w.data = data
+	w.applied = cfg
+	return nil
+}
@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestSetLimitAndBurstTakeEffectImmediately(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Limit(0.1), 1, WithClock(clk)) // One message every 10s
+	sub := ps.Subscribe("topic")
+	ps.TryPublish("topic", 1)
+
+	ps.SetLimit(10) // Loosen: the next token is due in 100ms instead of 10s
+	done := make(chan error, 1)
+	go func() { done <- ps.PublishWait(context.Background(), "topic", 2) }()
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("PublishWait: %v", err)
+	}
+
+	// Tighten: the burst is already used, so nothing more gets through right away
+	ps.SetLimit(1)
+	ps.SetBurst(2)
+	if ps.Limit() != 1 || ps.Burst() != 2 {
+		t.Errorf("Expected limit 1 and burst 2, got %v and %d", ps.Limit(), ps.Burst())
+	}
+	if ps.TryPublish("topic", 3) == nil {
+		t.Error("Expected the tightened limit to reject the message")
+	}
+	clk.Advance(time.Second)
+	if ps.TryPublish("topic", 4) != nil {
+		t.Error("Expected a token after one second at the new limit")
+	}
+
+	if n := drain(ps, "topic", sub); n != 3 {
+		t.Errorf("Expected 3 messages, got %d", n)
+	}
+}
+
+func TestSetLimitWakesWaitingPublishers(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Limit(0.1), 1, WithClock(clk)) // One message every 10s
+	sub := ps.Subscribe("topic")
+	ps.TryPublish("topic", 1)
+
+	done := make(chan error, 1)
+	go func() { done <- ps.PublishWait(context.Background(), "topic", 2) }()
+	clk.BlockUntil(1) // Waiting for the token due in 10s
+
+	ps.SetLimit(10) // The waiting publisher reserves again: its token is now due in 100ms
+	for i := 0; ; i++ {
+		clk.Advance(100 * time.Millisecond)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("PublishWait: %v", err)
+			}
+		case <-time.After(10 * time.Millisecond):
+			if i < 5 {
+				continue // The publisher may not have reserved again before this step
+			}
+			t.Fatal("Expected the waiting publisher to see the new limit")
+		}
+		break
+	}
+
+	if n := drain(ps, "topic", sub); n != 2 {
+		t.Errorf("Expected 2 messages, got %d", n)
+	}
+}
+
+func TestSetTopicLimitUpdatesLimiterInPlace(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithDefaultTopicLimit(100, 100))
+	sub := ps.Subscribe("metrics")
+
+	for i := 0; i < 10; i++ {
+		ps.Publish("metrics", i)
+	}
+
+	// Tokens already spent stay spent: 90 of the 100 remain, capped by the new burst of 5
+	ps.SetTopicLimit("metrics", 1, 5)
+	for i := 0; i < 10; i++ {
+		ps.Publish("metrics", i)
+	}
+	if r, b := ps.TopicLimit("metrics"); r != 1 || b != 5 {
+		t.Errorf("Expected topic limit 1/5, got %v/%d", r, b)
+	}
+
+	// Clearing restores the default
+	ps.ClearTopicLimit("metrics")
+	if r, b := ps.TopicLimit("metrics"); r != 100 || b != 100 {
+		t.Errorf("Expected the default topic limit 100/100, got %v/%d", r, b)
+	}
+
+	if n := drain(ps, "metrics", sub); n != 15 {
+		t.Errorf("Expected 10+5 messages, got %d", n)
+	}
+}
+
+func TestWatchConfigAppliesChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"limit": 100, "burst": 10, "topics": {"metrics": {"limit": 5, "burst": 1}}}`)
+
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk))
+	errs := make(chan error, 1)
+	stop, err := ps.WatchConfig(path, time.Second, func(err error) { errs <- err })
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	defer stop()
+
+	if ps.Limit() != 100 || ps.Burst() != 10 {
+		t.Errorf("Initial config not applied: limit %v, burst %d", ps.Limit(), ps.Burst())
+	}
+	if r, _ := ps.TopicLimit("metrics"); r != 5 {
+		t.Errorf("Initial topic limit not applied: %v", r)
+	}
+
+	// Tighten the global limit and remove the topic override
+	write(`{"limit": 1, "burst": 1, "publishers": {"batch-job": {"limit": -1, "burst": 0}}}`)
+	clk.Advance(time.Second)
+	waitFor(t, func() bool { return ps.Limit() == 1 })
+	if r, _ := ps.TopicLimit("metrics"); r != rate.Inf {
+		t.Errorf("Removed topic limit not cleared: %v", r)
+	}
+	if r, _ := ps.PublisherLimit("batch-job"); r != rate.Inf {
+		t.Errorf("Negative limit should mean unlimited, got %v", r)
+	}
+
+	// A broken file is reported and the previous limits stay in effect
+	write(`{"limit": `)
+	clk.Advance(time.Second)
+	if err := <-errs; err == nil {
+		t.Error("Expected a parse error")
+	}
+	if ps.Limit() != 1 {
+		t.Errorf("Limits changed by a broken file: %v", ps.Limit())
+	}
+}
+
+func TestWatchConfigRequiresReadableFile(t *testing.T) {
+	ps := NewPubSub[int](rate.Inf, 0)
+	if _, err := ps.WatchConfig(filepath.Join(t.TempDir(), "missing.json"), time.Second, nil); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+// waitFor polls cond until it holds, for changes applied by a background goroutine
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met within 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
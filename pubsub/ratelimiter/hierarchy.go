@@ -0,0 +1,275 @@
+package pubsub
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Bucket configures one node of a Hierarchy.
+type Bucket struct {
+	Limit  rate.Limit // Rate guaranteed to the node
+	Burst  int        // Burst of the node's own bucket, also used for borrowing
+	Borrow rate.Limit // Extra rate the node may take from its parent's unused capacity; 0 disables borrowing
+}
+
+// Hierarchy is a tree of token buckets for tenant, topic and publisher quotas. A publish to a topic
+// in the hierarchy must take a token at every level, from the publisher (if it has a node) up to the
+// tenant. A node whose own bucket is empty may borrow, up to Bucket.Borrow, as long as its parent still
+// has tokens, so capacity left unused by one topic or publisher can be used by its siblings.
+type Hierarchy struct {
+	mu      sync.Mutex
+	tenants map[string]*Node
+	topics  map[string]*Node // Topic nodes by topic name, across all tenants
+}
+
+// Node is a tenant, topic or publisher in a Hierarchy.
+type Node struct {
+	h        *Hierarchy
+	path     string
+	parent   *Node
+	bucket   Bucket
+	own      Limiter // The node's guaranteed rate
+	borrow   Limiter // Caps borrowing from the parent; nil if the node cannot borrow
+	children map[string]*Node
+
+	granted   atomic.Uint64
+	borrowed  atomic.Uint64
+	throttled atomic.Uint64
+}
+
+// NodeStats reports how a node's bucket has been used.
+type NodeStats struct {
+	Path      string     // Tenant, tenant/topic or tenant/topic/publisher
+	Limit     rate.Limit // Guaranteed rate
+	Borrow    rate.Limit // Maximum borrowed rate
+	Granted   uint64     // Tokens taken from the node's own bucket
+	Borrowed  uint64     // Tokens borrowed from the parent's capacity
+	Throttled uint64     // Publishes rejected or abandoned because the node had no token
+}
+
+// NewHierarchy creates an empty hierarchy. Add it to a PubSub with WithHierarchy.
+func NewHierarchy() *Hierarchy {
+	return &Hierarchy{tenants: make(map[string]*Node), topics: make(map[string]*Node)}
+}
+
+// Tenant adds a tenant, the root of a quota tree. Tenants cannot borrow. It panics if the tenant exists.
+func (h *Hierarchy) Tenant(name string, b Bucket) *Node {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.tenants[name]; ok {
+		panic(fmt.Sprintf("ratelimiter: tenant %q already exists", name))
+	}
+	b.Borrow = 0
+	n := h.newNode(name, nil, b)
+	h.tenants[name] = n
+	return n
+}
+
+// Node levels, from the root of a quota tree
+const (
+	tenantLevel = iota
+	topicLevel
+)
+
+// level returns how deep the node is in its quota tree
+func (n *Node) level() int {
+	level := tenantLevel
+	for p := n.parent; p != nil; p = p.parent {
+		level++
+	}
+	return level
+}
+
+// Topic adds a topic under a tenant. Topic names are global, so a topic belongs to one tenant;
+// it panics if the topic exists or if n is not a tenant.
+func (n *Node) Topic(name string, b Bucket) *Node {
+	if n.level() != tenantLevel {
+		panic(fmt.Sprintf("ratelimiter: topic %q must be added to a tenant, not %s", name, n.path))
+	}
+	h := n.h
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.topics[name]; ok {
+		panic(fmt.Sprintf("ratelimiter: topic %q already belongs to a tenant", name))
+	}
+	child := h.newNode(name, n, b)
+	h.topics[name] = child
+	return child
+}
+
+// Publisher adds a publisher identity under a topic. Publishers without a node are only
+// subject to the topic and tenant buckets. It panics if the publisher exists under the topic
+// or if n is not a topic.
+func (n *Node) Publisher(name string, b Bucket) *Node {
+	if n.level() != topicLevel {
+		panic(fmt.Sprintf("ratelimiter: publisher %q must be added to a topic, not %s", name, n.path))
+	}
+	h := n.h
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := n.children[name]; ok {
+		panic(fmt.Sprintf("ratelimiter: publisher %q already exists under %s", name, n.path))
+	}
+	return h.newNode(name, n, b)
+}
+
+// Stats returns the node's usage.
+func (n *Node) Stats() NodeStats {
+	return NodeStats{
+		Path:      n.path,
+		Limit:     n.bucket.Limit,
+		Borrow:    n.bucket.Borrow,
+		Granted:   n.granted.Load(),
+		Borrowed:  n.borrowed.Load(),
+		Throttled: n.throttled.Load(),
+	}
+}
+
+// Stats returns the usage of every node, ordered by path.
+func (h *Hierarchy) Stats() []NodeStats {
+	h.mu.Lock()
+	var nodes []*Node
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	for _, t := range h.tenants {
+		walk(t)
+	}
+	h.mu.Unlock()
+
+	stats := make([]NodeStats, len(nodes))
+	for i, n := range nodes {
+		stats[i] = n.Stats()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+	return stats
+}
+
+// newNode creates a node and links it to its parent. Must be called with h.mu held.
+func (h *Hierarchy) newNode(name string, parent *Node, b Bucket) *Node {
+	n := &Node{h: h, path: name, parent: parent, bucket: b, own: TokenBucket(b.Limit, b.Burst), children: make(map[string]*Node)}
+	if b.Borrow > 0 {
+		n.borrow = TokenBucket(b.Borrow, b.Burst)
+	}
+	if parent != nil {
+		n.path = parent.path + "/" + name
+		parent.children[name] = n
+	}
+	return n
+}
+
+// reserve takes a token at every level for a publish to topic by publisher, and returns how long
+// the caller must wait before acting on them. Topics outside the hierarchy are not limited.
+func (h *Hierarchy) reserve(topic, publisher string, now time.Time) (Reservation, bool) {
+	leaf := h.leaf(topic, publisher)
+	if leaf == nil {
+		return nil, true
+	}
+
+	pr := &pathReservation{}
+	for n := leaf; n != nil; n = n.parent {
+		r, borrowed := n.reserve(now)
+		if !r.OK() {
+			n.throttled.Add(1)
+			pr.CancelAt(now)
+			return nil, false
+		}
+		blocked := r.DelayFrom(now) > 0
+		if borrowed {
+			n.borrowed.Add(1)
+		} else {
+			n.granted.Add(1)
+		}
+		pr.steps = append(pr.steps, pathStep{node: n, r: r, borrowed: borrowed, blocked: blocked})
+	}
+	return pr, true
+}
+
+// leaf returns the lowest node a publish to topic by publisher is charged to, or nil if the topic is not in the hierarchy
+func (h *Hierarchy) leaf(topic, publisher string) *Node {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	leaf, ok := h.topics[topic]
+	if !ok {
+		return nil
+	}
+	if p, ok := leaf.children[publisher]; ok && publisher != "" {
+		leaf = p
+	}
+	return leaf
+}
+
+// reserve takes a token from the node's own bucket, or borrows one if the own bucket is empty.
+// Borrowing only takes the borrow allowance here; the parent's own token is taken at the next level.
+func (n *Node) reserve(now time.Time) (r Reservation, borrowed bool) {
+	r = n.own.ReserveN(now, 1)
+	if r.OK() && r.DelayFrom(now) == 0 {
+		return r, false
+	}
+
+	if n.borrow != nil {
+		b := n.borrow.ReserveN(now, 1)
+		if b.OK() && b.DelayFrom(now) == 0 {
+			r.CancelAt(now)
+			return b, true
+		}
+		b.CancelAt(now)
+	}
+	return r, false // Wait for the node's own bucket
+}
+
+// pathReservation holds the reservations of every level of a publish in a Hierarchy.
+// Node stats are counted when it is made and corrected if it is cancelled.
+type pathReservation struct {
+	steps     []pathStep
+	cancelled bool
+}
+
+// pathStep is the reservation made at one node
+type pathStep struct {
+	node     *Node
+	r        Reservation
+	borrowed bool // Borrowed from the parent rather than taken from the node's own bucket
+	blocked  bool // The node had no token available when the reservation was made
+}
+
+func (pr *pathReservation) OK() bool {
+	return true
+}
+
+func (pr *pathReservation) DelayFrom(now time.Time) time.Duration {
+	var delay time.Duration
+	for _, s := range pr.steps {
+		if d := s.r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
+
+func (pr *pathReservation) CancelAt(now time.Time) {
+	if pr.cancelled {
+		return
+	}
+	pr.cancelled = true
+	for _, s := range pr.steps {
+		s.r.CancelAt(now)
+		if s.borrowed {
+			s.node.borrowed.Add(^uint64(0))
+		} else {
+			s.node.granted.Add(^uint64(0))
+		}
+		if s.blocked {
+			s.node.throttled.Add(1) // This node is why the publish did not go through
+		}
+	}
+}
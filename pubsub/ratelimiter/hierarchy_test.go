@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestHierarchyBorrowingAndStats(t *testing.T) {
+	h := NewHierarchy()
+	acme := h.Tenant("acme", Bucket{Limit: 10, Burst: 10})
+	orders := acme.Topic("orders", Bucket{Limit: 5, Burst: 5})         // Cannot borrow
+	acme.Topic("events", Bucket{Limit: 5, Burst: 5, Borrow: rate.Inf}) // Can use the tenant's spare capacity
+
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithHierarchy(h))
+	ordersCh, eventsCh := ps.Subscribe("orders"), ps.Subscribe("events")
+
+	// events uses its own 5 tokens, then borrows 3 of the tenant's unused ones
+	for i := 0; i < 8; i++ {
+		ps.Publish("events", i)
+	}
+	// orders has 5 tokens of its own, but the tenant only has 2 left
+	for i := 0; i < 8; i++ {
+		ps.Publish("orders", i)
+	}
+
+	if n := drain(ps, "events", eventsCh); n != 8 {
+		t.Errorf("events: expected 8 messages, got %d", n)
+	}
+	if n := drain(ps, "orders", ordersCh); n != 2 {
+		t.Errorf("orders: expected the 2 tenant tokens left, got %d", n)
+	}
+
+	want := map[string]NodeStats{
+		"acme":        {Granted: 10, Throttled: 6},
+		"acme/events": {Granted: 5, Borrowed: 3},
+		"acme/orders": {Granted: 2},
+	}
+	stats := h.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("Expected stats for %d nodes, got %+v", len(want), stats)
+	}
+	for _, s := range stats {
+		w := want[s.Path]
+		if s.Granted != w.Granted || s.Borrowed != w.Borrowed || s.Throttled != w.Throttled {
+			t.Errorf("%s: expected granted/borrowed/throttled %d/%d/%d, got %d/%d/%d",
+				s.Path, w.Granted, w.Borrowed, w.Throttled, s.Granted, s.Borrowed, s.Throttled)
+		}
+	}
+	if s := orders.Stats(); s.Limit != 5 || s.Borrow != 0 {
+		t.Errorf("Unexpected orders configuration in stats: %+v", s)
+	}
+}
+
+func TestHierarchyPublisherLevel(t *testing.T) {
+	h := NewHierarchy()
+	topic := h.Tenant("acme", Bucket{Limit: 100, Burst: 100}).Topic("orders", Bucket{Limit: 100, Burst: 100})
+	topic.Publisher("batch-job", Bucket{Limit: 1, Burst: 2})
+
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithHierarchy(h))
+	sub := ps.Subscribe("orders")
+
+	for i := 0; i < 5; i++ {
+		ps.PublishAs("batch-job", "orders", i) // Limited by its own node
+		ps.PublishAs("web", "orders", i)       // No node: only topic and tenant apply
+	}
+	ps.Publish("unmanaged", 0) // Topics outside the hierarchy are not limited
+
+	if n := drain(ps, "orders", sub); n != 7 {
+		t.Errorf("Expected 2 batch-job and 5 web messages, got %d", n)
+	}
+	if s := topic.Stats(); s.Granted != 7 {
+		t.Errorf("Expected the topic to be charged for every publisher, got %d", s.Granted)
+	}
+}
+
+func TestHierarchyRejectsDuplicateTopics(t *testing.T) {
+	h := NewHierarchy()
+	h.Tenant("a", Bucket{Limit: 1, Burst: 1}).Topic("orders", Bucket{Limit: 1, Burst: 1})
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a topic in two tenants")
+		}
+	}()
+	h.Tenant("b", Bucket{Limit: 1, Burst: 1}).Topic("orders", Bucket{Limit: 1, Burst: 1})
+}
+
+func TestHierarchyRejectsNodesAtTheWrongLevel(t *testing.T) {
+	h := NewHierarchy()
+	tenant := h.Tenant("acme", Bucket{Limit: 1, Burst: 1})
+	topic := tenant.Topic("orders", Bucket{Limit: 1, Burst: 1})
+	publisher := topic.Publisher("billing", Bucket{Limit: 1, Burst: 1})
+
+	for name, add := range map[string]func(){
+		"topic under a topic":         func() { topic.Topic("events", Bucket{Limit: 1, Burst: 1}) },
+		"topic under a publisher":     func() { publisher.Topic("events", Bucket{Limit: 1, Burst: 1}) },
+		"publisher under a tenant":    func() { tenant.Publisher("billing", Bucket{Limit: 1, Burst: 1}) },
+		"publisher under a publisher": func() { publisher.Publisher("audit", Bucket{Limit: 1, Burst: 1}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for a %s", name)
+				}
+			}()
+			add()
+		}()
+	}
+}
@@ -0,0 +1,232 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limit is a token bucket configuration
+type limit struct {
+	rate  rate.Limit
+	burst int
+}
+
+// unlimited is the limit of keys that are not rate limited
+var unlimited = limit{rate: rate.Inf}
+
+// keyedLimiters lazily creates one token bucket per key, such as a topic or a publisher identity.
+// Limiters that go unused for idleTimeout are evicted so that thousands of short-lived keys do not leak memory.
+type keyedLimiters struct {
+	mu          sync.Mutex
+	algorithm   Algorithm        // Creates the limiter of each key
+	limits      map[string]limit // Explicit per-key limits
+	fallback    limit            // Limit for keys without an explicit one
+	idleTimeout time.Duration
+	limiters    map[string]*keyedLimiter
+	lastSweep   time.Time // When idle limiters were last evicted
+}
+
+// keyedLimiter is a limiter together with the last time it was used
+type keyedLimiter struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+func newKeyedLimiters(algorithm Algorithm, limits map[string]limit, fallback limit, idleTimeout time.Duration) *keyedLimiters {
+	return &keyedLimiters{
+		algorithm:   algorithm,
+		limits:      limits,
+		fallback:    fallback,
+		idleTimeout: idleTimeout,
+		limiters:    make(map[string]*keyedLimiter),
+	}
+}
+
+// get returns the limiter for key, creating it if needed, or nil if the key is unlimited.
+func (k *keyedLimiters) get(key string, now time.Time) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.evictIdle(now)
+	if l, ok := k.limiters[key]; ok {
+		l.lastUsed = now
+		return l.limiter
+	}
+
+	lim, ok := k.limits[key]
+	if !ok {
+		lim = k.fallback
+	}
+	if lim.rate == rate.Inf {
+		return nil // Nothing to track
+	}
+
+	l := &keyedLimiter{limiter: k.algorithm(lim.rate, lim.burst), lastUsed: now}
+	k.limiters[key] = l
+	return l.limiter
+}
+
+// evictIdle removes limiters unused for idleTimeout. It scans at most once per idleTimeout,
+// so a limiter is evicted between one and two timeouts after its last use.
+// Must be called with k.mu held.
+func (k *keyedLimiters) evictIdle(now time.Time) {
+	if now.Sub(k.lastSweep) < k.idleTimeout {
+		return
+	}
+	k.lastSweep = now
+	for key, l := range k.limiters {
+		if now.Sub(l.lastUsed) >= k.idleTimeout {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// set changes the limit of key. A limiter already in use is updated in place,
+// so tokens already taken and pending reservations carry over.
+func (k *keyedLimiters) set(key string, lim limit, now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.limits == nil {
+		k.limits = make(map[string]limit)
+	}
+	k.limits[key] = lim
+	k.apply(key, lim, now)
+}
+
+// clear removes the explicit limit of key so that it falls back to the default limit.
+func (k *keyedLimiters) clear(key string, now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.limits, key)
+	k.apply(key, k.fallback, now)
+}
+
+// limitOf returns the limit that applies to key
+func (k *keyedLimiters) limitOf(key string) limit {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if lim, ok := k.limits[key]; ok {
+		return lim
+	}
+	return k.fallback
+}
+
+// apply updates the limiter of key, if there is one, to lim. Must be called with k.mu held.
+func (k *keyedLimiters) apply(key string, lim limit, now time.Time) {
+	l, ok := k.limiters[key]
+	if !ok {
+		return // Created with the new limit on next use
+	}
+	if lim.rate == rate.Inf {
+		delete(k.limiters, key) // Unlimited keys are not tracked
+		return
+	}
+	l.limiter.SetLimitAt(now, lim.rate)
+	l.limiter.SetBurstAt(now, lim.burst)
+}
+
+// len returns the number of tracked limiters
+func (k *keyedLimiters) len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// charge is a number of tokens to take from a limiter
+type charge struct {
+	scope   string // Names the limit in RateLimitError
+	limiter Limiter
+	tokens  int
+}
+
+// charges returns the limiters a publish is subject to, outside the hierarchy, and what it costs in each
+func (ps *PubSub[T]) charges(publisher, topic string, size int, now time.Time) []charge {
+	charges := make([]charge, 0, 4)
+	add := func(scope string, l Limiter, tokens int) {
+		if l != nil {
+			charges = append(charges, charge{scope: scope, limiter: l, tokens: tokens})
+		}
+	}
+	add("global", ps.limiter, 1)
+	add("topic:"+topic, ps.topicLimiters.get(topic, now), 1)
+	if publisher != "" {
+		add("publisher:"+publisher, ps.publisherLimiters.get(publisher, now), 1)
+	}
+	if ps.byteLimiter != nil {
+		add("bytes", ps.byteLimiter, size)
+	}
+	return charges
+}
+
+// reserve takes one token from the global, topic and publisher limiters and from every level of the
+// hierarchy, and size tokens from the byte limiter, and returns how long the caller must wait before
+// acting on them. ok is false, and nothing is reserved, if one of the limiters can never grant the
+// tokens (for example a zero burst).
+func (ps *PubSub[T]) reserve(publisher, topic string, size int, now time.Time) (reservations []Reservation, delay time.Duration, ok bool) {
+	if ps.adaptive != nil {
+		ps.adapt(now)
+	}
+
+	ps.limitsMu.RLock() // Limits changed with ApplyConfig apply to the whole reservation or not at all
+	defer ps.limitsMu.RUnlock()
+	charges := ps.charges(publisher, topic, size, now)
+	reservations = make([]Reservation, 0, len(charges)+1)
+	for _, c := range charges {
+		r := c.limiter.ReserveN(now, c.tokens)
+		if !r.OK() {
+			cancel(reservations, now)
+			return nil, 0, false
+		}
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+
+	if ps.hierarchy != nil {
+		r, ok := ps.hierarchy.reserve(topic, publisher, now)
+		if !ok {
+			cancel(reservations, now)
+			return nil, 0, false
+		}
+		if r != nil {
+			reservations = append(reservations, r)
+			if d := r.DelayFrom(now); d > delay {
+				delay = d
+			}
+		}
+	}
+	return reservations, delay, true
+}
+
+// admit takes the tokens for a message of the given size from every applicable limiter.
+// A message is only let through if all of them have enough tokens; otherwise none is consumed,
+// so a message rejected by its topic limit does not use up the global budget. With shedding enabled,
+// the tokens are also given back, and ErrShed returned, if the message would dip into the reserve
+// held back from its priority. Otherwise the error is ErrRateLimited.
+func (ps *PubSub[T]) admit(publisher, topic string, priority Priority, size int) error {
+	now := ps.clock.Now()
+	reservations, delay, ok := ps.reserve(publisher, topic, size, now)
+	if !ok {
+		return ErrRateLimited
+	}
+	if delay > 0 {
+		cancel(reservations, now) // Give back the tokens already taken
+		return ErrRateLimited
+	}
+	if ps.shedder != nil && ps.shedder.sheds(ps.charges(publisher, topic, size, now), priority, now) {
+		cancel(reservations, now)
+		ps.shedder.shed[priority.index()].Add(1)
+		return ErrShed
+	}
+	return nil
+}
+
+// cancel returns the tokens of reservations that have not been acted on
+func cancel(reservations []Reservation, now time.Time) {
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+}
@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+// drain unsubscribes ch and returns how many messages it had buffered
+func drain[T any](ps *PubSub[T], topic string, ch chan T) int {
+	ps.Unsubscribe(topic, ch)
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestNoisyTopicDoesNotStarveOthers(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithTopicLimit("metrics", 1, 5))
+	metrics := ps.Subscribe("metrics")
+	alerts := ps.Subscribe("alerts")
+
+	for i := 0; i < 50; i++ {
+		ps.Publish("metrics", i)
+		ps.Publish("alerts", i)
+	}
+
+	if n := drain(ps, "metrics", metrics); n != 5 {
+		t.Errorf("metrics: expected the burst of 5 messages, got %d", n)
+	}
+	if n := drain(ps, "alerts", alerts); n != 50 {
+		t.Errorf("alerts: expected all 50 messages, got %d", n)
+	}
+}
+
+func TestDefaultTopicLimitGivesEachTopicItsOwnBucket(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk),
+		WithDefaultTopicLimit(1, 2),
+		WithTopicLimit("alerts", rate.Inf, 0))
+	a, b, alerts := ps.Subscribe("a"), ps.Subscribe("b"), ps.Subscribe("alerts")
+
+	for i := 0; i < 10; i++ {
+		ps.Publish("a", i)
+		ps.Publish("b", i)
+		ps.Publish("alerts", i)
+	}
+
+	if n := drain(ps, "a", a); n != 2 {
+		t.Errorf("a: expected 2 messages, got %d", n)
+	}
+	if n := drain(ps, "b", b); n != 2 {
+		t.Errorf("b: expected 2 messages, got %d", n)
+	}
+	if n := drain(ps, "alerts", alerts); n != 10 {
+		t.Errorf("alerts: expected the explicit unlimited topic to get all 10 messages, got %d", n)
+	}
+	if n := ps.topicLimiters.len(); n != 2 {
+		t.Errorf("Expected limiters only for the limited topics, got %d", n)
+	}
+}
+
+func TestPublisherLimits(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk),
+		WithDefaultPublisherLimit(1, 3),
+		WithPublisherLimit("batch-job", 1, 1))
+	sub := ps.Subscribe("events")
+
+	for i := 0; i < 10; i++ {
+		ps.PublishAs("batch-job", "events", i) // 1
+		ps.PublishAs("web-1", "events", i)     // 3
+		ps.PublishAs("web-2", "events", i)     // 3
+		ps.Publish("events", i)                // 10, anonymous publishers have no publisher limit
+	}
+
+	if n := drain(ps, "events", sub); n != 17 {
+		t.Errorf("Expected 1+3+3+10 messages, got %d", n)
+	}
+}
+
+func TestRejectedMessageDoesNotConsumeOtherBudgets(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 3, WithClock(clk), WithTopicLimit("metrics", 1, 1))
+	metrics := ps.Subscribe("metrics")
+	alerts := ps.Subscribe("alerts")
+
+	// Only the first metrics message passes its topic limit; the rejected ones
+	// must not use up the global burst that alerts depend on
+	for i := 0; i < 5; i++ {
+		ps.Publish("metrics", i)
+	}
+	for i := 0; i < 5; i++ {
+		ps.Publish("alerts", i)
+	}
+
+	if n := drain(ps, "metrics", metrics); n != 1 {
+		t.Errorf("metrics: expected 1 message, got %d", n)
+	}
+	if n := drain(ps, "alerts", alerts); n != 2 {
+		t.Errorf("alerts: expected the remaining global burst of 2, got %d", n)
+	}
+}
+
+func TestIdleLimitersAreEvicted(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk),
+		WithDefaultPublisherLimit(10, 10),
+		WithIdleTimeout(time.Minute))
+
+	for i := 0; i < 1000; i++ {
+		ps.PublishAs(fmt.Sprintf("publisher-%d", i), "events", i)
+	}
+	if n := ps.publisherLimiters.len(); n != 1000 {
+		t.Fatalf("Expected 1000 publisher limiters, got %d", n)
+	}
+
+	// One publisher stays active while the others go idle
+	clk.Advance(30 * time.Second)
+	ps.PublishAs("active", "events", 0)
+	clk.Advance(40 * time.Second)
+	ps.PublishAs("active", "events", 0)
+
+	if n := ps.publisherLimiters.len(); n != 1 {
+		t.Errorf("Expected only the active publisher's limiter to remain, got %d", n)
+	}
+}
@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter decides when events may happen. Every algorithm is configured with a rate and a burst;
+// for the window-based algorithms the window is burst/rate, so burst events are allowed per window.
+// All methods take the current time explicitly so that limiters can be driven by a fake clock.
+type Limiter interface {
+	// ReserveN reserves n events at now and reports when they may happen.
+	ReserveN(now time.Time, n int) Reservation
+	// TokensAt returns how many events could happen at now without waiting.
+	TokensAt(now time.Time) float64
+	Limit() rate.Limit
+	Burst() int
+	SetLimitAt(now time.Time, r rate.Limit)
+	SetBurstAt(now time.Time, burst int)
+}
+
+// Reservation is the result of Limiter.ReserveN. *rate.Reservation implements it.
+type Reservation interface {
+	// OK reports whether the events can ever be allowed. If not, nothing was reserved.
+	OK() bool
+	// DelayFrom returns how long to wait from now before acting on the reservation.
+	DelayFrom(now time.Time) time.Duration
+	// CancelAt gives back the reservation, as far as the algorithm allows, unless it was due before now.
+	CancelAt(now time.Time)
+}
+
+// Algorithm creates a Limiter for a rate and burst.
+type Algorithm func(r rate.Limit, burst int) Limiter
+
+// Compile-time checks that the built-in algorithms satisfy Algorithm
+var (
+	_ Algorithm = TokenBucket
+	_ Algorithm = SlidingWindowLog
+	_ Algorithm = FixedWindow
+	_ Algorithm = LeakyBucket
+	_ Algorithm = GCRA
+)
+
+// PerWindow converts a quota of n events per window into the rate and burst taken by an Algorithm.
+// For example PerWindow(100, time.Minute) with FixedWindow allows 100 events in every calendar minute.
+func PerWindow(n int, window time.Duration) (rate.Limit, int) {
+	return rate.Limit(float64(n) / window.Seconds()), n
+}
+
+// TokenBucket is the golang.org/x/time/rate token bucket: tokens refill continuously at r per second,
+// up to burst. A zero rate still grants the initial burst.
+func TokenBucket(r rate.Limit, burst int) Limiter {
+	return tokenBucket{rate.NewLimiter(r, burst)}
+}
+
+// tokenBucket adapts *rate.Limiter to Limiter
+type tokenBucket struct {
+	*rate.Limiter
+}
+
+func (b tokenBucket) ReserveN(now time.Time, n int) Reservation {
+	return b.Limiter.ReserveN(now, n)
+}
+
+// reservation implements Reservation for the built-in algorithms
+type reservation struct {
+	ok        bool
+	timeToAct time.Time
+	cancel    func() // Gives the reservation back; nil if there is nothing to give back
+}
+
+func (r *reservation) OK() bool {
+	return r.ok
+}
+
+func (r *reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return rate.InfDuration
+	}
+	if d := r.timeToAct.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func (r *reservation) CancelAt(now time.Time) {
+	if !r.ok || r.cancel == nil || r.timeToAct.Before(now) {
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+}
+
+// allowed returns a reservation that can be acted on immediately and needs no cancellation
+func allowed(now time.Time) *reservation {
+	return &reservation{ok: true, timeToAct: now}
+}
+
+// interval returns the time between events at rate r
+func interval(r rate.Limit) time.Duration {
+	return time.Duration(math.Round(float64(time.Second) / float64(r)))
+}
+
+// windowOf returns the window in which burst events are allowed at rate r
+func windowOf(r rate.Limit, burst int) time.Duration {
+	if w := time.Duration(math.Round(float64(burst) * float64(time.Second) / float64(r))); w > 0 {
+		return w
+	}
+	return 1
+}
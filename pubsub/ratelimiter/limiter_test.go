@@ -0,0 +1,178 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+var epoch = time.Unix(0, 0)
+
+// at returns the time d after epoch
+func at(d time.Duration) time.Time {
+	return epoch.Add(d)
+}
+
+// allowAt reports whether one event is allowed right now, giving the reservation back if not
+func allowAt(l Limiter, now time.Time) bool {
+	r := l.ReserveN(now, 1)
+	if !r.OK() || r.DelayFrom(now) > 0 {
+		r.CancelAt(now)
+		return false
+	}
+	return true
+}
+
+// allowedCount returns how many events are allowed when one is attempted at each time
+func allowedCount(l Limiter, times ...time.Time) int {
+	n := 0
+	for _, t := range times {
+		if allowAt(l, t) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	l := SlidingWindowLog(PerWindow(3, time.Second))
+
+	// Three events fit in any one-second window
+	if n := allowedCount(l, at(0), at(100*time.Millisecond), at(900*time.Millisecond), at(950*time.Millisecond)); n != 3 {
+		t.Errorf("Expected 3 events in the first window, got %d", n)
+	}
+	// The window slides: at 1s the first event has left it, the other two have not
+	if n := allowedCount(l, at(time.Second), at(1050*time.Millisecond)); n != 1 {
+		t.Errorf("Expected 1 event at 1s, got %d", n)
+	}
+	// A reservation waits exactly until the oldest event in the window expires
+	if d := l.ReserveN(at(1050*time.Millisecond), 1).DelayFrom(at(1050 * time.Millisecond)); d != 50*time.Millisecond {
+		t.Errorf("Expected a 50ms delay, got %v", d)
+	}
+	if l.ReserveN(at(0), 4).OK() {
+		t.Error("Expected a reservation larger than the window to fail")
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	l := FixedWindow(PerWindow(3, time.Second))
+
+	if n := allowedCount(l, at(0), at(100*time.Millisecond), at(900*time.Millisecond), at(950*time.Millisecond)); n != 3 {
+		t.Errorf("Expected 3 events in the first window, got %d", n)
+	}
+	// Unlike the sliding log, the next window starts with a full quota at the boundary
+	if n := allowedCount(l, at(time.Second), at(1050*time.Millisecond), at(1100*time.Millisecond), at(1200*time.Millisecond)); n != 3 {
+		t.Errorf("Expected 3 events in the second window, got %d", n)
+	}
+	// A reservation in a full window waits for the next one
+	if d := l.ReserveN(at(1500*time.Millisecond), 1).DelayFrom(at(1500 * time.Millisecond)); d != 500*time.Millisecond {
+		t.Errorf("Expected a 500ms delay, got %v", d)
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	l := LeakyBucket(10, 3) // Drains one event every 100ms, holds 3
+
+	// No bursts: back-to-back events must wait for their slot
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		r := l.ReserveN(at(0), 1)
+		if !r.OK() {
+			t.Fatalf("Reservation %d rejected", i)
+		}
+		delays = append(delays, r.DelayFrom(at(0)))
+	}
+	want := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("Expected delays %v, got %v", want, delays)
+			break
+		}
+	}
+
+	// The bucket is full until a slot drains
+	if l.ReserveN(at(0), 1).OK() {
+		t.Error("Expected a full bucket to reject the reservation")
+	}
+	if !l.ReserveN(at(100*time.Millisecond), 1).OK() {
+		t.Error("Expected room after one slot drained")
+	}
+
+	// In drop mode only evenly spaced events pass
+	l = LeakyBucket(10, 3)
+	if n := allowedCount(l, at(0), at(50*time.Millisecond), at(100*time.Millisecond), at(150*time.Millisecond), at(200*time.Millisecond)); n != 3 {
+		t.Errorf("Expected events at 0, 100ms and 200ms, got %d", n)
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	l := GCRA(10, 3) // 10/s with bursts of 3
+
+	if n := allowedCount(l, at(0), at(0), at(0), at(0)); n != 3 {
+		t.Errorf("Expected a burst of 3, got %d", n)
+	}
+	if d := l.ReserveN(at(0), 1).DelayFrom(at(0)); d != 100*time.Millisecond {
+		t.Errorf("Expected the next event after 100ms, got %v", d)
+	}
+	// The reservation above took the 100ms slot, the next one is at 200ms
+	if n := allowedCount(l, at(150*time.Millisecond), at(200*time.Millisecond)); n != 1 {
+		t.Errorf("Expected 1 event between 150ms and 200ms, got %d", n)
+	}
+	if l.ReserveN(at(0), 4).OK() {
+		t.Error("Expected a reservation larger than the burst to fail")
+	}
+}
+
+func TestAlgorithmsGiveBackCancelledReservations(t *testing.T) {
+	for name, algorithm := range map[string]Algorithm{
+		"TokenBucket":      TokenBucket,
+		"SlidingWindowLog": SlidingWindowLog,
+		"FixedWindow":      FixedWindow,
+		"LeakyBucket":      LeakyBucket,
+		"GCRA":             GCRA,
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := algorithm(1, 1)
+			if !allowAt(l, at(0)) {
+				t.Fatal("Expected the first event to be allowed")
+			}
+
+			// A future reservation that is cancelled must not delay the next event
+			l.ReserveN(at(0), 1).CancelAt(at(0))
+			r := l.ReserveN(at(time.Second), 1)
+			if !r.OK() || r.DelayFrom(at(time.Second)) != 0 {
+				t.Errorf("Cancelled reservation was not given back: delay %v", r.DelayFrom(at(time.Second)))
+			}
+		})
+	}
+}
+
+func TestAlgorithmsUnlimited(t *testing.T) {
+	for _, algorithm := range []Algorithm{TokenBucket, SlidingWindowLog, FixedWindow, LeakyBucket, GCRA} {
+		l := algorithm(rate.Inf, 0)
+		if n := allowedCount(l, at(0), at(0), at(0)); n != 3 {
+			t.Errorf("Expected an infinite rate to allow everything, got %d of 3", n)
+		}
+	}
+}
+
+func TestWithAlgorithm(t *testing.T) {
+	clk := fakeclock.New(epoch)
+	r, burst := PerWindow(2, time.Second)
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithAlgorithm(FixedWindow), WithTopicLimit("topic", r, burst))
+	sub := ps.Subscribe("topic")
+
+	for i := 0; i < 5; i++ {
+		ps.Publish("topic", i)
+	}
+	clk.Advance(time.Second)
+	for i := 0; i < 5; i++ {
+		ps.Publish("topic", i)
+	}
+
+	if n := drain(ps, "topic", sub); n != 4 {
+		t.Errorf("Expected 2 messages per window, got %d", n)
+	}
+}
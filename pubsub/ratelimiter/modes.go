@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRateLimited matches every *RateLimitError with errors.Is.
+var ErrRateLimited = errors.New("ratelimiter: rate limit exceeded")
+
+// Mode selects what Publish does when a rate limit is exceeded.
+type Mode int
+
+const (
+	// ModeDrop drops the message. This is the default.
+	ModeDrop Mode = iota
+	// ModeWait blocks the publisher until the message fits within every limit.
+	ModeWait
+	// ModeReserve reserves the tokens immediately and delivers the message once they become available,
+	// without blocking the publisher.
+	ModeReserve
+)
+
+// TryPublish publishes the message if it fits within the global and topic limits right now.
+// Otherwise it drops the message and returns a *RateLimitError telling when to retry.
+// It never blocks, whatever the Mode.
+func (ps *PubSub[T]) TryPublish(topic string, message T) error {
+	return ps.TryPublishAs("", topic, message)
+}
+
+// TryPublishAs is like TryPublish, but also charges the message to the publisher identity's own limit.
+// With WithShedding, the message has PriorityNormal and may be refused with ErrShed.
+func (ps *PubSub[T]) TryPublishAs(publisher, topic string, message T) error {
+	return ps.PublishPriorityAs(publisher, topic, PriorityNormal, message)
+}
+
+// PublishWait blocks until the message fits within the global and topic limits, then publishes it.
+// If ctx is done first, the reserved tokens are given back and ctx.Err() is returned.
+// It returns a *RateLimitError, which matches ErrRateLimited, if a limit can never let the message
+// through, such as a zero burst, or if a LeakyBucket limit is full.
+func (ps *PubSub[T]) PublishWait(ctx context.Context, topic string, message T) error {
+	return ps.publishWait(ctx, "", topic, message)
+}
+
+// PublishReserve reserves the tokens for the message and returns how long it will be held
+// before delivery. The message is delivered immediately if the delay is zero, or later from
+// another goroutine. It returns a *RateLimitError under the same conditions as PublishWait.
+func (ps *PubSub[T]) PublishReserve(topic string, message T) (time.Duration, error) {
+	return ps.publishReserve("", topic, message)
+}
+
+func (ps *PubSub[T]) publishWait(ctx context.Context, publisher, topic string, message T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	size := ps.size(message)
+	for {
+		changed := ps.limitsChangedChan()
+		now := ps.clock.Now()
+		reservations, delay, ok := ps.reserve(publisher, topic, size, now)
+		if !ok {
+			return ps.limitError(publisher, topic, size, now)
+		}
+		if delay == 0 {
+			break
+		}
+
+		timer := ps.clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-changed:
+			timer.Stop()
+			cancel(reservations, ps.clock.Now()) // Reserve again under the new limits
+			continue
+		case <-ctx.Done():
+			timer.Stop()
+			cancel(reservations, ps.clock.Now()) // Give back the tokens for other publishers
+			return ctx.Err()
+		}
+		break
+	}
+
+	ps.deliver(topic, message)
+	return nil
+}
+
+func (ps *PubSub[T]) publishReserve(publisher, topic string, message T) (time.Duration, error) {
+	now, size := ps.clock.Now(), ps.size(message)
+	_, delay, ok := ps.reserve(publisher, topic, size, now)
+	if !ok {
+		return 0, ps.limitError(publisher, topic, size, now)
+	}
+	if delay == 0 {
+		ps.deliver(topic, message)
+		return 0, nil
+	}
+
+	timer := ps.clock.NewTimer(delay)
+	go func() {
+		<-timer.C()
+		ps.deliver(topic, message)
+	}()
+	return delay, nil
+}
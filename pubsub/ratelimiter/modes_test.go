@@ -0,0 +1,171 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestTryPublishReportsDrops(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 2, WithClock(clk))
+	sub := ps.Subscribe("topic")
+
+	got := []error{ps.TryPublish("topic", 1), ps.TryPublish("topic", 2), ps.TryPublish("topic", 3)}
+	if got[0] != nil || got[1] != nil || !errors.Is(got[2], ErrRateLimited) {
+		t.Errorf("Expected the burst of 2 to be accepted and the third dropped, got %v", got)
+	}
+	clk.Advance(time.Second)
+	if ps.TryPublish("topic", 4) != nil {
+		t.Error("Expected a token after one second")
+	}
+	if n := drain(ps, "topic", sub); n != 3 {
+		t.Errorf("Expected 3 delivered messages, got %d", n)
+	}
+}
+
+func TestPublishWaitBlocksUntilTokenIsAvailable(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 1, WithClock(clk))
+	sub := ps.Subscribe("topic")
+
+	if err := ps.PublishWait(context.Background(), "topic", 1); err != nil {
+		t.Fatalf("First publish should not wait: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- ps.PublishWait(context.Background(), "topic", 2) }()
+
+	clk.BlockUntil(1) // The publisher is waiting for its token
+	if len(sub) != 1 {
+		t.Fatalf("Second message delivered before its token was available")
+	}
+
+	clk.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("PublishWait: %v", err)
+	}
+	if msg := <-sub; msg != 1 {
+		t.Errorf("Expected message 1, got %d", msg)
+	}
+	if msg := <-sub; msg != 2 {
+		t.Errorf("Expected message 2, got %d", msg)
+	}
+}
+
+func TestPublishWaitCancelReturnsToken(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 1, WithClock(clk))
+	sub := ps.Subscribe("topic")
+	ps.TryPublish("topic", 1) // Use up the burst
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ps.PublishWait(ctx, "topic", 2) }()
+	clk.BlockUntil(1)
+	cancelCtx()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// Without the cancelled reservation, a token is available again after one second
+	clk.Advance(time.Second)
+	if ps.TryPublish("topic", 3) != nil {
+		t.Error("Cancelled wait did not give back its token")
+	}
+	if n := drain(ps, "topic", sub); n != 2 {
+		t.Errorf("Expected messages 1 and 3, got %d messages", n)
+	}
+}
+
+func TestPublishWaitNeverSatisfiable(t *testing.T) {
+	ps := NewPubSub[int](1, 0)
+	if err := ps.PublishWait(context.Background(), "topic", 1); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for a zero burst, got %v", err)
+	}
+	if _, err := ps.PublishReserve("topic", 1); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for a zero burst, got %v", err)
+	}
+}
+
+func TestPublishReserveReportsDelay(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](2, 1, WithClock(clk))
+	sub := ps.Subscribe("topic")
+
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		d, err := ps.PublishReserve("topic", i)
+		if err != nil {
+			t.Fatalf("PublishReserve: %v", err)
+		}
+		delays = append(delays, d)
+	}
+	want := []time.Duration{0, 500 * time.Millisecond, time.Second}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("Message %d: expected delay %v, got %v", i, want[i], delays[i])
+		}
+	}
+
+	// Only the first message is delivered right away; the others follow as time passes, in order
+	if len(sub) != 1 {
+		t.Fatalf("Expected 1 message before any time passes, got %d", len(sub))
+	}
+	for i := 0; i < 3; i++ {
+		if msg := <-sub; msg != i {
+			t.Errorf("Expected message %d, got %d", i, msg)
+		}
+		clk.Advance(500 * time.Millisecond)
+	}
+}
+
+func TestWithModeAppliesToPublish(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithMode(ModeWait), WithTopicLimit("topic", 1, 1))
+	sub := ps.Subscribe("topic")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			ps.Publish("topic", i) // Blocks instead of dropping
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		if msg := <-sub; msg != i {
+			t.Errorf("Expected message %d, got %d", i, msg)
+		}
+		if i < 2 {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+	}
+	<-done
+}
+
+func TestPublishReturnsRateLimitError(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 1, WithClock(clk))
+	sub := ps.Subscribe("topic")
+
+	if err := ps.Publish("topic", 1); err != nil {
+		t.Fatalf("Expected the first message to be accepted, got %v", err)
+	}
+	var rle *RateLimitError
+	if err := ps.PublishAs("billing", "topic", 2); !errors.As(err, &rle) {
+		t.Fatalf("Expected a *RateLimitError, got %v", err)
+	} else if rle.RetryAfter != time.Second {
+		t.Errorf("Expected to retry after 1s, got %v", rle.RetryAfter)
+	}
+
+	if n := drain(ps, "topic", sub); n != 1 {
+		t.Errorf("Expected 1 delivered message, got %d", n)
+	}
+}
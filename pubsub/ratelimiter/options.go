@@ -0,0 +1,131 @@
+package pubsub
+
+import (
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
+	"golang.org/x/time/rate"
+)
+
+// DefaultIdleTimeout is how long a per-topic or per-publisher limiter may go unused
+// before it is evicted, when WithIdleTimeout is not given.
+const DefaultIdleTimeout = 10 * time.Minute
+
+// Option configures a PubSub created by NewPubSub.
+type Option func(*options)
+
+// options holds the settings collected from Options
+type options struct {
+	clock                 clock.Clock      // Time source for the rate limiters
+	algorithm             Algorithm        // Creates the global, topic and publisher limiters
+	topicLimits           map[string]limit // Explicit per-topic limits
+	publisherLimits       map[string]limit // Explicit per-publisher limits
+	defaultTopicLimit     limit            // Limit for topics without an explicit one
+	defaultPublisherLimit limit            // Limit for publishers without an explicit one
+	idleTimeout           time.Duration    // Idle time after which keyed limiters are evicted
+	mode                  Mode             // What Publish does when a limit is exceeded
+	adaptive              *AdaptiveConfig  // Adaptive global limit; nil if disabled
+	byteLimit             *limit           // Global limit in bytes per second; nil if disabled
+	sizer                 any              // Sizer[T] given to WithSizer
+	hierarchy             *Hierarchy       // Tenant, topic and publisher quota tree
+	shedding              Shedding         // Burst held back from each priority; nil if disabled
+}
+
+// WithClock sets the time source the rate limiters read. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithAlgorithm sets the rate-limiting algorithm used by the global, topic and publisher limits.
+// Defaults to TokenBucket.
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = a
+	}
+}
+
+// WithMode sets what Publish and PublishAs do when a limit is exceeded. Defaults to ModeDrop.
+func WithMode(m Mode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// WithAdaptive makes the global limit adapt to subscriber feedback, see AdaptiveConfig.
+// The limit passed to NewPubSub is the starting rate.
+func WithAdaptive(cfg AdaptiveConfig) Option {
+	return func(o *options) {
+		o.adaptive = &cfg
+	}
+}
+
+// WithByteLimit limits the bytes published per second across all topics, in addition to the
+// message limits. Each message is charged its size as measured by the Sizer; the burst must be
+// at least the size of the largest message, or that message can never be published.
+func WithByteLimit(bytesPerSecond rate.Limit, burst int) Option {
+	return func(o *options) {
+		o.byteLimit = &limit{rate: bytesPerSecond, burst: burst}
+	}
+}
+
+// WithSizer sets how WithByteLimit measures messages. It is required unless the message type
+// is string or []byte, which are measured by their length. T must be the PubSub's message type.
+func WithSizer[T any](s Sizer[T]) Option {
+	return func(o *options) {
+		o.sizer = s
+	}
+}
+
+// WithHierarchy enforces the tenant, topic and publisher quotas of h in addition to the other limits.
+func WithHierarchy(h *Hierarchy) Option {
+	return func(o *options) {
+		o.hierarchy = h
+	}
+}
+
+// WithTopicLimit limits the messages published to one topic, in addition to the global limit.
+func WithTopicLimit(topic string, r rate.Limit, burst int) Option {
+	return func(o *options) {
+		if o.topicLimits == nil {
+			o.topicLimits = make(map[string]limit)
+		}
+		o.topicLimits[topic] = limit{rate: r, burst: burst}
+	}
+}
+
+// WithDefaultTopicLimit sets the limit for every topic without a WithTopicLimit.
+// Each topic gets its own bucket. Defaults to rate.Inf (no per-topic limit).
+func WithDefaultTopicLimit(r rate.Limit, burst int) Option {
+	return func(o *options) {
+		o.defaultTopicLimit = limit{rate: r, burst: burst}
+	}
+}
+
+// WithPublisherLimit limits the messages one publisher identity may send through PublishAs.
+func WithPublisherLimit(publisher string, r rate.Limit, burst int) Option {
+	return func(o *options) {
+		if o.publisherLimits == nil {
+			o.publisherLimits = make(map[string]limit)
+		}
+		o.publisherLimits[publisher] = limit{rate: r, burst: burst}
+	}
+}
+
+// WithDefaultPublisherLimit sets the limit for every publisher identity without a WithPublisherLimit.
+// Each publisher gets its own bucket. Defaults to rate.Inf (no per-publisher limit).
+func WithDefaultPublisherLimit(r rate.Limit, burst int) Option {
+	return func(o *options) {
+		o.defaultPublisherLimit = limit{rate: r, burst: burst}
+	}
+}
+
+// WithIdleTimeout sets how long a per-topic or per-publisher limiter may go unused before it is evicted.
+// An evicted limiter is recreated with a full burst on next use, so the timeout should be
+// at least the time the limit takes to refill its burst.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import "golang.org/x/time/rate"
+
+// Overflow selects which message a subscriber loses when its buffer is full.
+type Overflow int
+
+const (
+	// DropNewest drops the message being published. This is the default.
+	DropNewest Overflow = iota
+	// DropOldest discards the oldest buffered message to make room for the new one.
+	DropOldest
+)
+
+// Pace configures a subscription created by SubscribePaced.
+type Pace struct {
+	Limit    rate.Limit // Messages per second released to the subscriber
+	Burst    int        // Messages that may be released back to back. Values below 1 are treated as 1
+	Buffer   int        // Messages held while waiting to be released. Defaults to 100
+	Overflow Overflow   // What happens when the buffer is full
+}
+
+// SubscribePaced is like Subscribe, but messages are released to the returned channel at no more
+// than pace.Limit per second, whatever the publish rate. Messages wait in a buffer of pace.Buffer;
+// when it is full, pace.Overflow decides which message is dropped.
+// Unsubscribing closes the channel and discards messages still waiting to be released.
+// It panics if pace.Limit is not positive.
+func (ps *PubSub[T]) SubscribePaced(topic string, pace Pace) chan T {
+	if pace.Limit <= 0 {
+		panic("ratelimiter: pace limit must be positive")
+	}
+	if pace.Burst < 1 {
+		pace.Burst = 1
+	}
+	if pace.Buffer <= 0 {
+		pace.Buffer = 100
+	}
+
+	sub := &subscriber[T]{
+		ch:       make(chan T, pace.Buffer),
+		out:      make(chan T),
+		overflow: pace.Overflow,
+		quit:     make(chan struct{}),
+	}
+	go ps.pace(sub, ps.algorithm(pace.Limit, pace.Burst))
+	ps.add(topic, sub)
+	return sub.out
+}
+
+// pace moves messages from the subscriber's buffer to its channel, waiting on the limiter between them
+func (ps *PubSub[T]) pace(sub *subscriber[T], limiter Limiter) {
+	defer close(sub.out)
+	for {
+		var msg T
+		select {
+		case m, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			msg = m
+		case <-sub.quit:
+			return
+		}
+
+		if !ps.awaitRelease(sub, limiter) {
+			return
+		}
+
+		select {
+		case sub.out <- msg:
+		case <-sub.quit:
+			return
+		}
+	}
+}
+
+// awaitRelease waits until the limiter lets one message through. A rejected reservation, such as a full
+// LeakyBucket, is retried after one interval of the limit. It returns false if the subscription is closed first.
+func (ps *PubSub[T]) awaitRelease(sub *subscriber[T], limiter Limiter) bool {
+	for {
+		now := ps.clock.Now()
+		r := limiter.ReserveN(now, 1)
+		delay := interval(limiter.Limit()) // Give the limiter time to make room before retrying
+		if r.OK() {
+			delay = r.DelayFrom(now)
+		}
+
+		if delay > 0 {
+			timer := ps.clock.NewTimer(delay)
+			select {
+			case <-timer.C():
+			case <-sub.quit:
+				timer.Stop()
+				return false
+			}
+		}
+		if r.OK() {
+			return true
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestSubscribePacedReleasesAtConfiguredRate(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk))
+	sub := ps.SubscribePaced("topic", Pace{Limit: 2, Burst: 1})
+
+	// Published all at once, far faster than the pace
+	for i := 0; i < 3; i++ {
+		ps.Publish("topic", i)
+	}
+
+	if msg := <-sub; msg != 0 {
+		t.Fatalf("Expected message 0, got %d", msg)
+	}
+	for i := 1; i < 3; i++ {
+		clk.BlockUntil(1) // The pacer waits for the next release
+		select {
+		case msg := <-sub:
+			t.Fatalf("Message %d released early", msg)
+		default:
+		}
+		clk.Advance(500 * time.Millisecond)
+		if msg := <-sub; msg != i {
+			t.Fatalf("Expected message %d, got %d", i, msg)
+		}
+	}
+
+	ps.Unsubscribe("topic", sub)
+	if _, ok := <-sub; ok {
+		t.Error("Expected the paced channel to be closed by Unsubscribe")
+	}
+}
+
+func TestSubscribePacedOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		overflow Overflow
+		want     []int
+	}{
+		{name: "drop-newest", overflow: DropNewest, want: []int{0, 1, 2, 3}},
+		{name: "drop-oldest", overflow: DropOldest, want: []int{0, 1, 4, 5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := fakeclock.New(time.Unix(0, 0))
+			ps := NewPubSub[int](rate.Inf, 0, WithClock(clk))
+			sub := ps.SubscribePaced("topic", Pace{Limit: 1, Burst: 1, Buffer: 2, Overflow: tc.overflow})
+
+			ps.Publish("topic", 0)
+			<-sub // Uses up the burst
+			ps.Publish("topic", 1)
+			clk.BlockUntil(1) // The pacer holds message 1 until its release time
+
+			// Four messages for a buffer of two
+			for i := 2; i < 6; i++ {
+				ps.Publish("topic", i)
+			}
+			if ps.Dropped() != 2 {
+				t.Errorf("Expected 2 dropped messages, got %d", ps.Dropped())
+			}
+
+			got := []int{0}
+			for len(got) < len(tc.want) {
+				clk.BlockUntil(1)
+				clk.Advance(time.Second)
+				got = append(got, <-sub)
+			}
+			for i := range tc.want {
+				if got[i] != tc.want[i] {
+					t.Fatalf("Expected %v, got %v", tc.want, got)
+				}
+			}
+			ps.Shutdown()
+		})
+	}
+}
+
+func TestSubscribePacedWithEveryAlgorithm(t *testing.T) {
+	for name, algorithm := range map[string]Algorithm{
+		"token-bucket":       TokenBucket,
+		"sliding-window-log": SlidingWindowLog,
+		"fixed-window":       FixedWindow,
+		"leaky-bucket":       LeakyBucket,
+		"gcra":               GCRA,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clk := fakeclock.New(time.Unix(0, 0))
+			ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithAlgorithm(algorithm))
+			sub := ps.SubscribePaced("topic", Pace{Limit: 2}) // Default burst of 1
+
+			for i := 0; i < 3; i++ {
+				ps.Publish("topic", i)
+			}
+
+			// Advance in small steps until each message is released, but never faster than the pace
+			for i := 0; i < 3; i++ {
+				for step := 0; ; step++ {
+					if step == 40 {
+						t.Fatalf("Message %d not released after %v", i, clk.Since(time.Unix(0, 0)))
+					}
+					select {
+					case msg := <-sub:
+						if msg != i {
+							t.Fatalf("Expected message %d, got %d", i, msg)
+						}
+						if elapsed := clk.Since(time.Unix(0, 0)); elapsed < time.Duration(i)*500*time.Millisecond {
+							t.Fatalf("Message %d released after %v, faster than the pace", i, elapsed)
+						}
+					case <-time.After(10 * time.Millisecond):
+						clk.Advance(125 * time.Millisecond)
+						continue
+					}
+					break
+				}
+			}
+			ps.Unsubscribe("topic", sub)
+		})
+	}
+}
+
+func TestSubscribePacedRejectsNonPositiveLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a zero pace limit")
+		}
+	}()
+	NewPubSub[int](rate.Inf, 0).SubscribePaced("topic", Pace{})
+}
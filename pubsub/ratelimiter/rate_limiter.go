@@ -1,27 +1,92 @@
 package pubsub
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
 	"golang.org/x/time/rate"
 )
 
 // PubSub manages publishers and subscribers for any message type
+// The subscriber registry is copy-on-write so Publish takes no broker locks
 type PubSub[T any] struct {
-	subscribers map[string]map[chan T]struct{} // Map of topics to a set of subscriber channels
-	mu          sync.RWMutex                   // Read-Write lock to manage concurrent access
-	limiter     *rate.Limiter                  // Rate limiter for publishers
+	topics    atomic.Pointer[map[string]*topic[T]] // Immutable map of topics, replaced when a topic is added or removed
+	mu        sync.Mutex                           // Serializes Subscribe, Unsubscribe and Shutdown
+	limiter   Limiter                              // Global rate limiter shared by all publishers
+	clock     clock.Clock                          // Time source for the rate limiters
+	algorithm Algorithm                            // Creates limiters, including subscription pacers
+	mode      Mode                                 // What Publish does when a limit is exceeded
+
+	topicLimiters     *keyedLimiters // Per-topic limiters
+	publisherLimiters *keyedLimiters // Per-publisher limiters, used by PublishAs
+	byteLimiter       Limiter        // Limits bytes per second as measured by sizer; nil if disabled
+	sizer             Sizer[T]       // Measures messages for byteLimiter
+	hierarchy         *Hierarchy     // Tenant, topic and publisher quota tree; nil if disabled
+	adaptive          *adaptive      // Adjusts the global limit from subscriber feedback; nil if disabled
+	shedder           *shedder       // Sheds low-priority messages under pressure; nil if disabled
+
+	limitsMu      sync.RWMutex  // Held for reading while reserving and for writing while limits change
+	limitsChanged chan struct{} // Closed and replaced whenever limits change, to wake waiting publishers
+	dropped       atomic.Uint64 // Messages dropped because a subscriber's buffer was full
+}
+
+// topic holds the current immutable snapshot of a topic's subscribers
+type topic[T any] struct {
+	subscribers atomic.Pointer[[]*subscriber[T]] // Replaced, never modified in place
+}
+
+// subscriber guards a subscription channel so it is never sent on after being closed
+type subscriber[T any] struct {
+	ch       chan T        // Buffer that Publish sends into
+	out      chan T        // Channel handed to the subscriber; the same as ch unless delivery is paced
+	overflow Overflow      // What send does when ch is full
+	quit     chan struct{} // Closed with ch to stop a pacer; nil for unpaced subscribers
+	mu       sync.Mutex    // Held while sending and while closing
+	closed   bool
 }
 
 // NewPubSub initializes a new PubSub instance for a specific type with a rate limit.
-// limit: maximum number of messages allowed per second
+// limit: maximum number of messages allowed per second, across all topics and publishers
 // burst: maximum burst size
-func NewPubSub[T any](limit rate.Limit, burst int) *PubSub[T] {
-	return &PubSub[T]{
-		subscribers: make(map[string]map[chan T]struct{}),
-		limiter:     rate.NewLimiter(limit, burst),
+// Per-topic and per-publisher limits are configured with Options.
+func NewPubSub[T any](limit rate.Limit, burst int, opts ...Option) *PubSub[T] {
+	o := options{
+		clock:                 clock.Real(),
+		algorithm:             TokenBucket,
+		defaultTopicLimit:     unlimited,
+		defaultPublisherLimit: unlimited,
+		idleTimeout:           DefaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
 	}
+
+	ps := &PubSub[T]{
+		limiter:           o.algorithm(limit, burst),
+		clock:             o.clock,
+		algorithm:         o.algorithm,
+		hierarchy:         o.hierarchy,
+		mode:              o.mode,
+		topicLimiters:     newKeyedLimiters(o.algorithm, o.topicLimits, o.defaultTopicLimit, o.idleTimeout),
+		publisherLimiters: newKeyedLimiters(o.algorithm, o.publisherLimits, o.defaultPublisherLimit, o.idleTimeout),
+		limitsChanged:     make(chan struct{}),
+	}
+	if o.byteLimit != nil {
+		ps.byteLimiter = o.algorithm(o.byteLimit.rate, o.byteLimit.burst)
+		ps.sizer = sizerFor[T](o.sizer)
+	}
+	if o.adaptive != nil {
+		ps.adaptive = newAdaptive(*o.adaptive, limit, ps.clock.Now())
+		ps.limiter.SetLimitAt(ps.clock.Now(), clampRate(limit, ps.adaptive.cfg.Min, ps.adaptive.cfg.Max))
+	}
+	if o.shedding != nil {
+		ps.shedder = newShedder(o.shedding)
+	}
+	ps.topics.Store(&map[string]*topic[T]{})
+	return ps
 }
 
 // Subscribe adds a new subscriber to a specific topic.
@@ -29,70 +94,136 @@ func NewPubSub[T any](limit rate.Limit, burst int) *PubSub[T] {
 func (ps *PubSub[T]) Subscribe(topic string) chan T {
 	// Create a buffered channel to prevent blocking during message delivery
 	ch := make(chan T, 100) // Buffered channel size is set to 100 for high throughput
+	ps.add(topic, &subscriber[T]{ch: ch, out: ch})
+	return ch
+}
+
+// add registers a subscriber with a topic
+func (ps *PubSub[T]) add(topic string, sub *subscriber[T]) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	// Initialize the topic in the map if it doesn't exist
-	if ps.subscribers[topic] == nil {
-		ps.subscribers[topic] = make(map[chan T]struct{})
-	}
-
-	// Add the subscriber channel to the topic
-	ps.subscribers[topic][ch] = struct{}{}
-	return ch
+	// Swap in a new snapshot of the topic's subscribers that includes the channel
+	t := ps.getOrCreateTopic(topic)
+	current := *t.subscribers.Load()
+	next := make([]*subscriber[T], len(current), len(current)+1)
+	copy(next, current)
+	next = append(next, sub)
+	t.subscribers.Store(&next)
 }
 
 // Publish sends a message to all subscribers of a given topic.
-// Ensures rate limiting for publishers.
-func (ps *PubSub[T]) Publish(topic string, message T) {
-	// Enforce rate limiting
-	if !ps.limiter.Allow() {
-		fmt.Println("Rate limit exceeded. Dropping message:", message)
+// Ensures rate limiting for publishers: what happens when the global or the topic limit is
+// exceeded depends on the Mode. By default the message is dropped and a *RateLimitError returned.
+func (ps *PubSub[T]) Publish(topic string, message T) error {
+	return ps.PublishAs("", topic, message)
+}
+
+// PublishAs is like Publish, but also charges the message to the publisher identity's own limit.
+// An empty publisher is anonymous and only subject to the global and topic limits.
+// It returns the error of TryPublishAs, PublishWait or PublishReserve, depending on the Mode.
+func (ps *PubSub[T]) PublishAs(publisher, topic string, message T) error {
+	switch ps.mode {
+	case ModeWait:
+		return ps.publishWait(context.Background(), publisher, topic, message)
+	case ModeReserve:
+		_, err := ps.publishReserve(publisher, topic, message)
+		return err
+	default:
+		return ps.TryPublishAs(publisher, topic, message)
+	}
+}
+
+// deliver fans the message out to the topic's current subscribers
+func (ps *PubSub[T]) deliver(topic string, message T) {
+	// Load the current snapshot; no broker lock is taken during fan-out
+	t, ok := (*ps.topics.Load())[topic]
+	if !ok {
 		return
 	}
 
-	ps.mu.RLock() // Acquire read lock to allow concurrent publishing
-	defer ps.mu.RUnlock()
-
-	var wg sync.WaitGroup // WaitGroup to ensure all goroutines finish
-
-	// Iterate over all subscriber channels for the topic
-	for ch := range ps.subscribers[topic] {
-		wg.Add(1)
-		// Deliver message to each subscriber in a separate goroutine
-		go func(c chan T) {
-			defer wg.Done()
-			// Send the message or drop it if the channel is full
-			select {
-			case c <- message:
-				// Message successfully delivered
-			default:
-				// Channel is full; drop the message to avoid blocking
-				fmt.Println("Subscriber is too slow. Dropping message.")
-			}
-		}(ch)
+	// Sends never block, so the snapshot is delivered to in a simple loop
+	for _, sub := range *t.subscribers.Load() {
+		if !sub.send(message) {
+			ps.dropped.Add(1)
+		}
 	}
+}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+// Dropped returns the number of deliveries dropped because a subscriber's buffer was full.
+func (ps *PubSub[T]) Dropped() uint64 {
+	return ps.dropped.Load()
+}
+
+// send delivers the message or drops it if the channel is full, and reports whether it was not dropped.
+// It is a no-op once the subscriber has been closed.
+func (s *subscriber[T]) send(message T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true // Unsubscribed after the snapshot was taken
+	}
+
+	select {
+	case s.ch <- message:
+		// Message successfully delivered
+		return true
+	default:
+	}
+
+	// Channel is full; drop a message to avoid blocking
+	fmt.Println("Subscriber is too slow. Dropping message.")
+	if s.overflow == DropOldest {
+		select {
+		case <-s.ch: // Make room by discarding the oldest buffered message
+		default:
+		}
+		select {
+		case s.ch <- message:
+		default:
+		}
+	}
+	return false
+}
+
+// close closes the subscriber channel once no send is in progress
+func (s *subscriber[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch) // Close the channel to clean up resources
+	if s.quit != nil {
+		close(s.quit)
+	}
 }
 
 // Unsubscribe removes a subscriber from a specific topic.
 // The channel is closed to signal the subscriber that no more messages will be sent.
 func (ps *PubSub[T]) Unsubscribe(topic string, ch chan T) {
-	ps.mu.Lock() // Acquire write lock to modify the subscriber map
+	ps.mu.Lock() // Serialize with other writers
 	defer ps.mu.Unlock()
 
 	// Check if the topic exists
-	if subscribers, ok := ps.subscribers[topic]; ok {
-		// Remove the subscriber channel if it exists
-		if _, exists := subscribers[ch]; exists {
-			delete(subscribers, ch)
-			close(ch) // Close the channel to clean up resources
+	if t, ok := (*ps.topics.Load())[topic]; ok {
+		// Build a snapshot without the subscriber channel
+		current := *t.subscribers.Load()
+		next := make([]*subscriber[T], 0, len(current))
+		var removed *subscriber[T]
+		for _, sub := range current {
+			if sub.out == ch {
+				removed = sub
+				continue
+			}
+			next = append(next, sub)
+		}
+
+		if removed != nil {
+			t.subscribers.Store(&next)
+			removed.close()
 		}
 		// If no subscribers remain for the topic, remove the topic
-		if len(subscribers) == 0 {
-			delete(ps.subscribers, topic)
+		if len(next) == 0 {
+			ps.removeTopic(topic)
 		}
 	}
 }
@@ -100,16 +231,47 @@ func (ps *PubSub[T]) Unsubscribe(topic string, ch chan T) {
 // Shutdown gracefully shuts down the PubSub system by closing all channels.
 // This signals all subscribers that no more messages will be sent.
 func (ps *PubSub[T]) Shutdown() {
-	ps.mu.Lock() // Acquire write lock to prevent new subscriptions/publishing
+	ps.mu.Lock() // Serialize with other writers
 	defer ps.mu.Unlock()
 
-	// Iterate over all topics
-	for topic, subscribers := range ps.subscribers {
-		// Close all channels for each topic
-		for ch := range subscribers {
-			close(ch)
+	// Swap in an empty registry, then close all channels of the old one
+	old := ps.topics.Swap(&map[string]*topic[T]{})
+	for _, t := range *old {
+		for _, sub := range *t.subscribers.Load() {
+			sub.close()
+		}
+	}
+}
+
+// getOrCreateTopic returns the topic entry, adding it to a new copy of the topic map if needed.
+// Must be called with ps.mu held.
+func (ps *PubSub[T]) getOrCreateTopic(name string) *topic[T] {
+	current := *ps.topics.Load()
+	if t, ok := current[name]; ok {
+		return t
+	}
+
+	t := &topic[T]{}
+	t.subscribers.Store(&[]*subscriber[T]{})
+
+	next := make(map[string]*topic[T], len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[name] = t
+	ps.topics.Store(&next)
+	return t
+}
+
+// removeTopic swaps in a copy of the topic map without name.
+// Must be called with ps.mu held.
+func (ps *PubSub[T]) removeTopic(name string) {
+	current := *ps.topics.Load()
+	next := make(map[string]*topic[T], len(current))
+	for k, v := range current {
+		if k != name {
+			next[k] = v
 		}
-		// Remove the topic from the map
-		delete(ps.subscribers, topic)
 	}
+	ps.topics.Store(&next)
 }
@@ -5,53 +5,39 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
 	"golang.org/x/time/rate"
 )
 
 func TestRateLimiter(t *testing.T) {
 	// Set up the PubSub with a rate limit of 2 messages per second and a burst of 2
-	ps := NewPubSub[string](rate.Limit(2), 2)
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[string](rate.Limit(2), 2, WithClock(clk))
 
 	// Subscribe to a topic
 	sub := ps.Subscribe("test-topic")
 
-	// Channel to collect received messages
-	received := make(chan string, 10)
-
-	// Goroutine to process messages
-	go func() {
-		for msg := range sub {
-			received <- msg
-		}
-	}()
-
-	// Publish messages faster than the rate limit
-	for i := 0; i < 10; i++ {
+	// Publish 16 messages at 8 messages/sec, four times the rate limit.
+	// A 125ms step keeps the token arithmetic exact in floating point.
+	const step = 125 * time.Millisecond
+	const numMessages = 16
+	for i := 0; i < numMessages; i++ {
 		ps.Publish("test-topic", fmt.Sprintf("Message %d", i))
-		time.Sleep(100 * time.Millisecond) // Publish every 100ms (10 messages/sec)
+		clk.Advance(step)
 	}
 
-	// Allow time for the rate limiter to apply
-	time.Sleep(3 * time.Second)
-
-	// Check received messages
-	close(received)
-
-	// Count the received messages
+	// Unsubscribing closes the channel, so the buffered messages can be counted without a reader goroutine
+	ps.Unsubscribe("test-topic", sub)
 	count := 0
-	for range received {
+	for range sub {
 		count++
 	}
 
-	// Expected number of messages received should not exceed the rate limit
-	// With a 2 messages/sec limit and 3 seconds, the max messages should be 6
-	if count > 6 {
-		t.Errorf("Rate limiter failed: received %d messages, expected <= 6", count)
-	} else {
-		t.Logf("Rate limiter passed: received %d messages within limit", count)
+	// The burst is allowed immediately, then one message per 500ms over the 1.875s between
+	// the first and last publish: 2 + 3 = 5
+	if count != 5 {
+		t.Errorf("Rate limiter failed: received %d messages, expected 5", count)
 	}
 
-	// Cleanup
-	ps.Unsubscribe("test-topic", sub)
 	ps.Shutdown()
 }
@@ -0,0 +1,24 @@
+package pubsub
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSendFromStaleSnapshotAfterUnsubscribe(t *testing.T) {
+	ps := NewPubSub[int](rate.Inf, 1)
+	ch := ps.Subscribe("topic")
+	stale := *(*ps.topics.Load())["topic"].subscribers.Load() // As held by a publisher mid-fan-out
+
+	ps.Unsubscribe("topic", ch)
+	if !stale[0].send(1) {
+		t.Error("Expected a send to an unsubscribed subscriber not to count as a drop")
+	}
+	if msg, ok := <-ch; ok {
+		t.Errorf("Expected the channel to be closed without the message, got %d", msg)
+	}
+	if _, ok := (*ps.topics.Load())["topic"]; ok {
+		t.Error("Expected the topic to be removed with its last subscriber")
+	}
+}
@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitError is returned when a publish is rejected by a rate limit. It tells the publisher
+// when to retry and how much of each quota is left, so it can back off instead of retrying blindly.
+type RateLimitError struct {
+	RetryAfter time.Duration // How long until every limit has room for the message; rate.InfDuration if never
+	RetryAt    time.Time     // The time of the publish plus RetryAfter; zero if never
+	Quotas     []Quota       // Every limit the publish was subject to
+
+	err error // ErrShed if the message was shed, otherwise ErrRateLimited
+}
+
+// Quota describes one limit at the time of a rejected publish.
+type Quota struct {
+	Scope      string        // "global", "topic:<name>", "publisher:<name>", "bytes" or "hierarchy:<path>"
+	Limit      rate.Limit    // Rate of the limit
+	Burst      int           // Burst of the limit
+	Remaining  int           // Tokens available right away; bytes for the "bytes" scope
+	RetryAfter time.Duration // How long until this limit has room for the message
+}
+
+func (e *RateLimitError) Error() string {
+	var blocking []string
+	for _, q := range e.Quotas {
+		if q.RetryAfter > 0 {
+			blocking = append(blocking, q.Scope)
+		}
+	}
+	if e.RetryAfter == rate.InfDuration {
+		return fmt.Sprintf("ratelimiter: rate limit exceeded, message can never be published (%s)", strings.Join(blocking, ", "))
+	}
+	return fmt.Sprintf("ratelimiter: rate limit exceeded, retry after %v (%s)", e.RetryAfter, strings.Join(blocking, ", "))
+}
+
+// Unwrap lets errors.Is(err, ErrRateLimited) match, and errors.Is(err, ErrShed) if the message was shed.
+func (e *RateLimitError) Unwrap() error {
+	if e.err == nil {
+		return ErrRateLimited
+	}
+	return e.err
+}
+
+// limitError describes every limit that applies to a publish at now. Each limiter is asked when it
+// could grant the tokens by making a reservation and giving it back straight away.
+func (ps *PubSub[T]) limitError(publisher, topic string, size int, now time.Time) *RateLimitError {
+	return ps.limitErrorHolding(publisher, topic, size, 0, now)
+}
+
+// shedError is like limitError for a message that was shed: it can be retried once every limit
+// has room for it on top of the reserve held back from its priority.
+func (ps *PubSub[T]) shedError(publisher, topic string, priority Priority, size int, now time.Time) *RateLimitError {
+	e := ps.limitErrorHolding(publisher, topic, size, ps.shedder.reserve[priority.index()], now)
+	e.err = ErrShed
+	return e
+}
+
+// limitErrorHolding builds a limitError where the fraction reserve of every limited burst must be left over
+func (ps *PubSub[T]) limitErrorHolding(publisher, topic string, size int, reserve float64, now time.Time) *RateLimitError {
+	e := &RateLimitError{}
+	for _, c := range ps.charges(publisher, topic, size, now) {
+		tokens := c.tokens
+		if c.limiter.Limit() != rate.Inf {
+			tokens += int(math.Ceil(reserve * float64(c.limiter.Burst())))
+		}
+		e.add(Quota{
+			Scope:      c.scope,
+			Limit:      c.limiter.Limit(),
+			Burst:      c.limiter.Burst(),
+			Remaining:  remaining(c.limiter, now),
+			RetryAfter: probe(c.limiter, now, tokens),
+		})
+	}
+	if ps.hierarchy != nil {
+		for n := ps.hierarchy.leaf(topic, publisher); n != nil; n = n.parent {
+			retryAfter := probe(n.own, now, 1)
+			if n.borrow != nil {
+				retryAfter = min(retryAfter, probe(n.borrow, now, 1))
+			}
+			e.add(Quota{
+				Scope:      "hierarchy:" + n.path,
+				Limit:      n.bucket.Limit,
+				Burst:      n.bucket.Burst,
+				Remaining:  remaining(n.own, now),
+				RetryAfter: retryAfter,
+			})
+		}
+	}
+
+	if e.RetryAfter != rate.InfDuration {
+		e.RetryAt = now.Add(e.RetryAfter)
+	}
+	return e
+}
+
+// add records a quota; the publish can be retried once the slowest limit has room
+func (e *RateLimitError) add(q Quota) {
+	e.Quotas = append(e.Quotas, q)
+	if q.RetryAfter > e.RetryAfter {
+		e.RetryAfter = q.RetryAfter
+	}
+}
+
+// probe returns how long l would make a request for n tokens wait, without keeping the reservation
+func probe(l Limiter, now time.Time, n int) time.Duration {
+	r := l.ReserveN(now, n)
+	defer r.CancelAt(now)
+	return r.DelayFrom(now)
+}
+
+// remaining returns the whole tokens l has available at now
+func remaining(l Limiter, now time.Time) int {
+	if l.Limit() == rate.Inf {
+		return l.Burst()
+	}
+	if t := l.TokensAt(now); t > 0 {
+		return int(t)
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitErrorReportsRetryAndQuota(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](2, 3, WithClock(clk), WithTopicLimit("metrics", 1, 1))
+
+	if err := ps.TryPublish("metrics", 1); err != nil {
+		t.Fatalf("First publish: %v", err)
+	}
+	err := ps.TryPublish("metrics", 2)
+
+	var rle *RateLimitError
+	if !errors.As(err, &rle) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected a *RateLimitError matching ErrRateLimited, got %v", err)
+	}
+	if rle.RetryAfter != time.Second || !rle.RetryAt.Equal(time.Unix(1, 0)) {
+		t.Errorf("Expected retry after 1s at %v, got %v at %v", time.Unix(1, 0), rle.RetryAfter, rle.RetryAt)
+	}
+
+	want := []Quota{
+		{Scope: "global", Limit: 2, Burst: 3, Remaining: 2, RetryAfter: 0},
+		{Scope: "topic:metrics", Limit: 1, Burst: 1, Remaining: 0, RetryAfter: time.Second},
+	}
+	if len(rle.Quotas) != len(want) {
+		t.Fatalf("Expected quotas %+v, got %+v", want, rle.Quotas)
+	}
+	for i := range want {
+		if rle.Quotas[i] != want[i] {
+			t.Errorf("Quota %d: expected %+v, got %+v", i, want[i], rle.Quotas[i])
+		}
+	}
+	if !strings.Contains(err.Error(), "retry after 1s (topic:metrics)") {
+		t.Errorf("Error message does not name the retry delay and blocking limit: %q", err)
+	}
+
+	// Reporting the error must not use up any tokens: retrying at RetryAt succeeds
+	clk.Set(rle.RetryAt)
+	if err := ps.TryPublish("metrics", 3); err != nil {
+		t.Errorf("Retry at RetryAt failed: %v", err)
+	}
+}
+
+func TestRateLimitErrorIncludesPublisherAndHierarchy(t *testing.T) {
+	h := NewHierarchy()
+	h.Tenant("acme", Bucket{Limit: 10, Burst: 1}).Topic("orders", Bucket{Limit: 10, Burst: 10})
+
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](rate.Inf, 0, WithClock(clk), WithHierarchy(h), WithPublisherLimit("svc", 5, 5))
+	ps.TryPublishAs("svc", "orders", 1)
+
+	var rle *RateLimitError
+	if !errors.As(ps.TryPublishAs("svc", "orders", 2), &rle) {
+		t.Fatal("Expected a *RateLimitError")
+	}
+	var scopes []string
+	for _, q := range rle.Quotas {
+		scopes = append(scopes, q.Scope)
+	}
+	if got := strings.Join(scopes, ","); got != "global,publisher:svc,hierarchy:acme/orders,hierarchy:acme" {
+		t.Errorf("Unexpected scopes %s", got)
+	}
+	if rle.RetryAfter != 100*time.Millisecond {
+		t.Errorf("Expected the tenant to allow a retry after 100ms, got %v", rle.RetryAfter)
+	}
+}
+
+func TestRateLimitErrorNeverSatisfiable(t *testing.T) {
+	ps := NewPubSub[int](1, 0)
+	err := ps.PublishWait(context.Background(), "topic", 1)
+
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected a *RateLimitError, got %v", err)
+	}
+	if rle.RetryAfter != rate.InfDuration || !rle.RetryAt.IsZero() {
+		t.Errorf("Expected no retry time, got %v at %v", rle.RetryAfter, rle.RetryAt)
+	}
+}
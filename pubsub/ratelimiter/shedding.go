@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrShed is returned when a message is shed to keep capacity for higher priorities.
+// It matches ErrRateLimited with errors.Is.
+var ErrShed = fmt.Errorf("%w: message shed to keep capacity for higher priorities", ErrRateLimited)
+
+// Priority ranks messages for load shedding. Higher priorities are shed last.
+type Priority int
+
+const (
+	// PriorityLow messages are shed first.
+	PriorityLow Priority = iota
+	// PriorityNormal is the priority of messages published without one.
+	PriorityNormal
+	// PriorityHigh messages may use the whole burst of every limit.
+	PriorityHigh
+)
+
+const numPriorities = int(PriorityHigh) + 1
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// index clamps p to a known priority
+func (p Priority) index() int {
+	return min(max(int(p), int(PriorityLow)), int(PriorityHigh))
+}
+
+// Shedding is the fraction of every limit's burst, between 0 and 1, held back from messages of each priority.
+// A message is only published if, after taking its tokens, at least that fraction of each limit's burst
+// is still available, so under pressure lower priorities are refused while higher ones borrow the reserve.
+// Priorities that are not listed may use the whole burst.
+type Shedding map[Priority]float64
+
+// DefaultShedding keeps half of every burst from low-priority messages and a fifth from normal ones.
+var DefaultShedding = Shedding{PriorityLow: 0.5, PriorityNormal: 0.2}
+
+// WithShedding enables priority-aware load shedding, see Shedding. It applies to Publish in ModeDrop,
+// TryPublish and PublishPriority; publishes without a priority are PriorityNormal.
+// It panics if a fraction is outside [0, 1].
+func WithShedding(s Shedding) Option {
+	return func(o *options) {
+		for p, reserve := range s {
+			if reserve < 0 || reserve > 1 {
+				panic("ratelimiter: shedding reserve of priority " + p.String() + " is outside [0, 1]")
+			}
+		}
+		o.shedding = s
+	}
+}
+
+// PublishPriority publishes the message if it fits within every limit right now, leaving the reserve
+// that Shedding holds back from its priority. Otherwise the message is dropped and a *RateLimitError
+// returned, which also matches ErrShed if only the reserve was in the way. It never blocks, whatever the Mode.
+func (ps *PubSub[T]) PublishPriority(topic string, priority Priority, message T) error {
+	return ps.PublishPriorityAs("", topic, priority, message)
+}
+
+// PublishPriorityAs is like PublishPriority, but also charges the message to the publisher identity's own limit.
+func (ps *PubSub[T]) PublishPriorityAs(publisher, topic string, priority Priority, message T) error {
+	size := ps.size(message)
+	if err := ps.admit(publisher, topic, priority, size); err != nil {
+		if errors.Is(err, ErrShed) {
+			return ps.shedError(publisher, topic, priority, size, ps.clock.Now())
+		}
+		return ps.limitError(publisher, topic, size, ps.clock.Now())
+	}
+	ps.deliver(topic, message)
+	return nil
+}
+
+// Shed returns the number of messages of the given priority that were shed.
+func (ps *PubSub[T]) Shed(priority Priority) uint64 {
+	if ps.shedder == nil {
+		return 0
+	}
+	return ps.shedder.shed[priority.index()].Load()
+}
+
+// shedder holds back part of every limit's burst from lower priorities
+type shedder struct {
+	reserve [numPriorities]float64 // Fraction of each burst held back, by priority
+	shed    [numPriorities]atomic.Uint64
+}
+
+func newShedder(s Shedding) *shedder {
+	sh := &shedder{}
+	for p, reserve := range s {
+		sh.reserve[p.index()] = reserve
+	}
+	return sh
+}
+
+// sheds reports whether a message of the given priority, whose tokens have already been taken, dips into
+// the reserve of one of the limiters it was charged to. Unlimited limiters have no reserve.
+func (sh *shedder) sheds(charges []charge, priority Priority, now time.Time) bool {
+	reserve := sh.reserve[priority.index()]
+	if reserve == 0 {
+		return false
+	}
+	for _, c := range charges {
+		if c.limiter.Limit() == rate.Inf {
+			continue
+		}
+		if c.limiter.TokensAt(now) < reserve*float64(c.limiter.Burst()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+)
+
+func TestSheddingReservesBurstForHigherPriorities(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 10, WithClock(clk), WithShedding(DefaultShedding))
+	ch := ps.Subscribe("quotes")
+
+	// Low priority may only take the half of the burst that is not held back
+	published := 0
+	for i := 0; i < 10; i++ {
+		if ps.PublishPriority("quotes", PriorityLow, i) == nil {
+			published++
+		}
+	}
+	if published != 5 {
+		t.Errorf("Expected 5 low-priority messages, got %d", published)
+	}
+
+	// Normal priority borrows up to the last fifth, high priority the rest
+	for _, tc := range []struct {
+		priority Priority
+		want     int
+	}{{PriorityNormal, 3}, {PriorityHigh, 2}} {
+		published = 0
+		for i := 0; i < 10; i++ {
+			if ps.PublishPriority("quotes", tc.priority, i) == nil {
+				published++
+			}
+		}
+		if published != tc.want {
+			t.Errorf("Expected %d %v-priority messages, got %d", tc.want, tc.priority, published)
+		}
+	}
+
+	if got := len(ch); got != 10 {
+		t.Errorf("Expected the whole burst of 10 to be delivered, got %d", got)
+	}
+	if shed := ps.Shed(PriorityLow); shed != 5 {
+		t.Errorf("Expected 5 low-priority messages shed, got %d", shed)
+	}
+	if shed := ps.Shed(PriorityNormal); shed != 7 {
+		t.Errorf("Expected 7 normal-priority messages shed, got %d", shed)
+	}
+	if shed := ps.Shed(PriorityHigh); shed != 0 {
+		t.Errorf("Expected no high-priority messages shed, got %d", shed)
+	}
+}
+
+func TestShedErrorAndRateLimitError(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 2, WithClock(clk), WithShedding(Shedding{PriorityLow: 0.5}))
+
+	if err := ps.PublishPriority("quotes", PriorityLow, 1); err != nil {
+		t.Fatalf("First low-priority publish: %v", err)
+	}
+	err := ps.PublishPriority("quotes", PriorityLow, 2)
+	if !errors.Is(err, ErrShed) || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrShed matching ErrRateLimited, got %v", err)
+	}
+
+	// Shedding gave back the tokens, so a high-priority message still fits; then the bucket is empty
+	if err := ps.PublishPriority("quotes", PriorityHigh, 3); err != nil {
+		t.Fatalf("High-priority publish: %v", err)
+	}
+	var rle *RateLimitError
+	if err := ps.PublishPriority("quotes", PriorityHigh, 4); !errors.As(err, &rle) || errors.Is(err, ErrShed) {
+		t.Errorf("Expected a *RateLimitError once the burst is used up, got %v", err)
+	}
+}
+
+func TestShedErrorReportsRetryAndQuotas(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](1, 4, WithClock(clk), WithShedding(Shedding{PriorityNormal: 0.5}))
+
+	for i := 0; i < 2; i++ {
+		if err := ps.TryPublish("quotes", i); err != nil {
+			t.Fatalf("Publish %d within the unreserved half of the burst: %v", i, err)
+		}
+	}
+	err := ps.TryPublishAs("billing", "quotes", 2)
+	var rle *RateLimitError
+	if !errors.As(err, &rle) || !errors.Is(err, ErrShed) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected a *RateLimitError matching ErrShed and ErrRateLimited, got %v", err)
+	}
+	// 2 tokens are left and the reserve keeps 2, so the message fits once one more token is added
+	if rle.RetryAfter != time.Second || !rle.RetryAt.Equal(clk.Now().Add(time.Second)) {
+		t.Errorf("Expected to retry after 1s, got %v at %v", rle.RetryAfter, rle.RetryAt)
+	}
+	if len(rle.Quotas) != 1 || rle.Quotas[0].Scope != "global" || rle.Quotas[0].Remaining != 2 {
+		t.Errorf("Expected the global quota with 2 tokens remaining, got %+v", rle.Quotas)
+	}
+
+	clk.Advance(rle.RetryAfter)
+	if err := ps.TryPublish("quotes", 3); err != nil {
+		t.Errorf("Expected the retry to be accepted, got %v", err)
+	}
+}
+
+func TestSheddingAppliesToTopicLimitsAndDefaultPriority(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](100, 100, WithClock(clk), WithTopicLimit("quotes", 1, 4),
+		WithShedding(Shedding{PriorityNormal: 0.5}))
+
+	// TryPublish is normal priority, so it keeps half of the topic burst free
+	published := 0
+	for i := 0; i < 4; i++ {
+		if ps.TryPublish("quotes", i) == nil {
+			published++
+		}
+	}
+	if published != 2 {
+		t.Errorf("Expected 2 normal-priority messages within the topic limit, got %d", published)
+	}
+	if err := ps.PublishPriority("quotes", PriorityHigh, 4); err != nil {
+		t.Errorf("Expected a high-priority message to borrow the topic reserve, got %v", err)
+	}
+}
+
+func TestWithSheddingRejectsInvalidReserve(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a reserve above 1")
+		}
+	}()
+	NewPubSub[int](1, 1, WithShedding(Shedding{PriorityLow: 1.5}))
+}
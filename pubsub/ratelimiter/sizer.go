@@ -0,0 +1,50 @@
+package pubsub
+
+import "fmt"
+
+// Sizer measures messages for byte-based rate limiting.
+type Sizer[T any] interface {
+	Size(message T) int
+}
+
+// SizerFunc adapts a function to Sizer.
+type SizerFunc[T any] func(message T) int
+
+func (f SizerFunc[T]) Size(message T) int {
+	return f(message)
+}
+
+// LenSizer measures strings and byte slices by their length. It is the default for those types.
+type LenSizer[T ~string | ~[]byte] struct{}
+
+func (LenSizer[T]) Size(message T) int {
+	return len(message)
+}
+
+// sizerFor returns the Sizer given to WithSizer, or the default for string and []byte messages.
+// It panics if no Sizer applies to T, since byte limits could not be enforced.
+func sizerFor[T any](configured any) Sizer[T] {
+	if configured != nil {
+		s, ok := configured.(Sizer[T])
+		if !ok {
+			panic(fmt.Sprintf("ratelimiter: WithSizer got %T, which does not measure %T messages", configured, *new(T)))
+		}
+		return s
+	}
+
+	defaults := []any{LenSizer[string]{}, LenSizer[[]byte]{}}
+	for _, d := range defaults {
+		if s, ok := d.(Sizer[T]); ok {
+			return s
+		}
+	}
+	panic(fmt.Sprintf("ratelimiter: WithByteLimit needs WithSizer for %T messages", *new(T)))
+}
+
+// size returns the size of a message, or 0 if byte limiting is disabled
+func (ps *PubSub[T]) size(message T) int {
+	if ps.sizer == nil {
+		return 0
+	}
+	return ps.sizer.Size(message)
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+	"golang.org/x/time/rate"
+)
+
+func TestByteLimitChargesBySize(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[string](rate.Inf, 0, WithClock(clk), WithByteLimit(1000, 1000))
+	sub := ps.Subscribe("topic")
+
+	// 1000 bytes of budget: one large message leaves room for 10 small ones, not 11
+	if ps.TryPublish("topic", strings.Repeat("x", 900)) != nil {
+		t.Fatal("Expected the 900-byte message to fit in the burst")
+	}
+	small := 0
+	for i := 0; i < 20; i++ {
+		if ps.TryPublish("topic", strings.Repeat("y", 10)) == nil {
+			small++
+		}
+	}
+	if small != 10 {
+		t.Errorf("Expected 10 small messages in the remaining 100 bytes, got %d", small)
+	}
+
+	// Half a second refills 500 bytes
+	clk.Advance(500 * time.Millisecond)
+	if ps.TryPublish("topic", strings.Repeat("z", 501)) == nil {
+		t.Error("Expected a 501-byte message to exceed the refilled budget")
+	}
+	if ps.TryPublish("topic", strings.Repeat("z", 500)) != nil {
+		t.Error("Expected a 500-byte message to fit the refilled budget")
+	}
+
+	if n := drain(ps, "topic", sub); n != 12 {
+		t.Errorf("Expected 12 messages, got %d", n)
+	}
+}
+
+func TestByteLimitAppliesAlongsideMessageLimit(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[[]byte](2, 2, WithClock(clk), WithByteLimit(rate.Inf, 0))
+	sub := ps.Subscribe("topic")
+
+	// Bytes are unlimited, but the message limit still applies
+	for i := 0; i < 5; i++ {
+		ps.Publish("topic", []byte("payload"))
+	}
+	if n := drain(ps, "topic", sub); n != 2 {
+		t.Errorf("Expected the message burst of 2, got %d", n)
+	}
+}
+
+func TestByteLimitRejectsMessagesLargerThanBurst(t *testing.T) {
+	ps := NewPubSub[[]byte](rate.Inf, 0, WithByteLimit(100, 100))
+	if err := ps.PublishWait(context.Background(), "topic", make([]byte, 101)); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for a message larger than the byte burst, got %v", err)
+	}
+}
+
+func TestWithSizer(t *testing.T) {
+	type event struct{ body []byte }
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[event](rate.Inf, 0, WithClock(clk), WithByteLimit(100, 100),
+		WithSizer[event](SizerFunc[event](func(e event) int { return len(e.body) })))
+
+	if ps.TryPublish("topic", event{body: make([]byte, 60)}) != nil {
+		t.Error("Expected the first message to fit")
+	}
+	if ps.TryPublish("topic", event{body: make([]byte, 60)}) == nil {
+		t.Error("Expected the second message to exceed the byte budget")
+	}
+}
+
+func TestByteLimitNeedsSizer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic without a Sizer for a struct message type")
+		}
+	}()
+	NewPubSub[struct{}](rate.Inf, 0, WithByteLimit(100, 100))
+}
@@ -0,0 +1,227 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SlidingWindowLog allows at most burst events in any window of burst/r, keeping the time of every
+// event in the window. It is exact, unlike FixedWindow, at the cost of memory proportional to burst.
+// A zero rate lets nothing through.
+func SlidingWindowLog(r rate.Limit, burst int) Limiter {
+	return &slidingWindowLog{limit: r, burst: burst}
+}
+
+type slidingWindowLog struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	log   []time.Time // Times of reserved events, oldest first
+}
+
+func (l *slidingWindowLog) ReserveN(now time.Time, n int) Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf {
+		return allowed(now)
+	}
+	if l.limit <= 0 || n > l.burst {
+		return &reservation{}
+	}
+
+	// Forget events that have left the window
+	window := windowOf(l.limit, l.burst)
+	i := 0
+	for i < len(l.log) && !l.log[i].After(now.Add(-window)) {
+		i++
+	}
+	l.log = append(l.log[:0], l.log[i:]...)
+
+	// Reservations are granted in order, so never before the latest one
+	t := now
+	if len(l.log) > 0 && l.log[len(l.log)-1].After(t) {
+		t = l.log[len(l.log)-1]
+	}
+	// Wait until enough of the oldest events have left the window
+	if k := len(l.log) + n - l.burst; k > 0 {
+		if expiry := l.log[k-1].Add(window); expiry.After(t) {
+			t = expiry
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		l.log = append(l.log, t)
+	}
+	return &reservation{ok: true, timeToAct: t, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.remove(t, n)
+	}}
+}
+
+// remove deletes up to n events logged at t. Must be called with l.mu held.
+func (l *slidingWindowLog) remove(t time.Time, n int) {
+	kept := l.log[:0]
+	for _, e := range l.log {
+		if n > 0 && e.Equal(t) {
+			n--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	l.log = kept
+}
+
+func (l *slidingWindowLog) TokensAt(now time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf || l.limit <= 0 {
+		return float64(l.burst)
+	}
+	start := now.Add(-windowOf(l.limit, l.burst))
+	used := 0
+	for _, e := range l.log {
+		if e.After(start) {
+			used++
+		}
+	}
+	return float64(l.burst - used)
+}
+
+func (l *slidingWindowLog) Limit() rate.Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *slidingWindowLog) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+func (l *slidingWindowLog) SetLimitAt(now time.Time, r rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = r
+}
+
+func (l *slidingWindowLog) SetBurstAt(now time.Time, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = burst
+}
+
+// FixedWindow allows at most burst events in each consecutive window of burst/r, aligned to the Unix epoch.
+// It needs constant memory but allows up to twice the burst across a window boundary.
+// A zero rate lets nothing through.
+func FixedWindow(r rate.Limit, burst int) Limiter {
+	return &fixedWindow{limit: r, burst: burst, counts: make(map[int64]int)}
+}
+
+type fixedWindow struct {
+	mu     sync.Mutex
+	limit  rate.Limit
+	burst  int
+	counts map[int64]int // Reserved events per window index
+	last   int64         // Latest window index reserved into
+}
+
+func (l *fixedWindow) ReserveN(now time.Time, n int) Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf {
+		return allowed(now)
+	}
+	if l.limit <= 0 || n > l.burst {
+		return &reservation{}
+	}
+
+	window := int64(windowOf(l.limit, l.burst))
+	current := now.UnixNano() / window
+	for idx := range l.counts {
+		if idx < current {
+			delete(l.counts, idx) // Past windows no longer matter
+		}
+	}
+
+	// Reservations are granted in order, so never in a window before the latest one
+	idx := current
+	if l.last > idx {
+		idx = l.last
+	}
+	for l.counts[idx]+n > l.burst {
+		idx++
+	}
+	l.counts[idx] += n
+	l.last = idx
+
+	t := now
+	if idx > current {
+		t = time.Unix(0, idx*window)
+	}
+	return &reservation{ok: true, timeToAct: t, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.counts[idx] -= n; l.counts[idx] <= 0 {
+			delete(l.counts, idx)
+		}
+	}}
+}
+
+func (l *fixedWindow) TokensAt(now time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == rate.Inf || l.limit <= 0 {
+		return float64(l.burst)
+	}
+	current := now.UnixNano() / int64(windowOf(l.limit, l.burst))
+	return float64(l.burst - l.counts[current])
+}
+
+func (l *fixedWindow) Limit() rate.Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *fixedWindow) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+func (l *fixedWindow) SetLimitAt(now time.Time, r rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = r
+	l.rewindow(now)
+}
+
+func (l *fixedWindow) SetBurstAt(now time.Time, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst = burst
+	l.rewindow(now)
+}
+
+// rewindow carries the events reserved in the current and future windows over to the current
+// window of the new size, since window indexes change meaning. Must be called with l.mu held.
+func (l *fixedWindow) rewindow(now time.Time) {
+	var reserved int
+	for _, c := range l.counts {
+		reserved += c
+	}
+	l.counts = make(map[int64]int)
+	l.last = 0
+	if l.limit == rate.Inf || l.limit <= 0 {
+		return
+	}
+	current := now.UnixNano() / int64(windowOf(l.limit, l.burst))
+	if reserved > 0 {
+		l.counts[current] = reserved
+	}
+	l.last = current
+}
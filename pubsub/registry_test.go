@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionChurnDuringPublish(t *testing.T) {
+	ps := NewPubSub[int]()
+
+	// A stable subscriber must see every message in order despite the churn around it
+	stable := ps.Subscribe("churn")
+	received := make(chan int, 2000)
+	go func() {
+		for msg := range stable {
+			received <- msg
+		}
+		close(received)
+	}()
+
+	stop := make(chan struct{})
+	var churnWg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		churnWg.Add(1)
+		go func() {
+			defer churnWg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ch := ps.Subscribe("churn")
+				go func() {
+					for range ch {
+					}
+				}()
+				ps.Unsubscribe("churn", ch)
+			}
+		}()
+	}
+
+	const numMessages = 1000
+	for i := 0; i < numMessages; i++ {
+		ps.Publish("churn", i)
+		if i%100 == 0 {
+			time.Sleep(time.Millisecond) // Let the stable subscriber catch up
+		}
+	}
+	close(stop)
+	churnWg.Wait()
+	ps.Shutdown()
+
+	expected := 0
+	for msg := range received {
+		if msg != expected {
+			t.Fatalf("Stable subscriber got %d, expected %d", msg, expected)
+		}
+		expected++
+	}
+	if expected != numMessages {
+		t.Errorf("Stable subscriber received %d messages, expected %d", expected, numMessages)
+	}
+}
+
+// BenchmarkPublishDuringSubscriptionChurn measures Publish while another goroutine keeps
+// subscribing and unsubscribing on the same topic. churn-ops/s shows that writers are not starved.
+func BenchmarkPublishDuringSubscriptionChurn(b *testing.B) {
+	ps := NewPubSub[int]()
+	for i := 0; i < 10; i++ {
+		ch := ps.Subscribe("contended")
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+
+	var churnOps atomic.Int64
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			ch := ps.Subscribe("contended")
+			ps.Unsubscribe("contended", ch)
+			churnOps.Add(1)
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ps.Publish("contended", 1)
+		}
+	})
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	close(stop)
+	<-done
+	ps.Shutdown()
+	b.ReportMetric(float64(churnOps.Load())/elapsed.Seconds(), "churn-ops/s")
+}
@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// HeaderError is the header set on a reply when the responder's handler failed.
+const HeaderError = "error"
+
+// inboxPrefix is prepended to auto-generated reply topics
+const inboxPrefix = "_INBOX."
+
+// Envelope wraps a payload with the routing metadata needed for request/reply.
+// Publishing Envelope values through a regular PubSub keeps the broker unaware of it.
+type Envelope[T any] struct {
+	ID            string            // Unique identifier of this message
+	CorrelationID string            // ID of the request a reply belongs to
+	ReplyTo       string            // Topic the responder should publish its reply to
+	Headers       map[string]string // Free-form metadata carried alongside the payload
+	Payload       T                 // The actual message
+}
+
+// Header returns the value stored under key, or "" if it is not set.
+func (e Envelope[T]) Header(key string) string {
+	return e.Headers[key]
+}
+
+// WithHeader returns a copy of the envelope with key set to value.
+// The header map is copied so that envelopes shared between subscribers are never mutated.
+func (e Envelope[T]) WithHeader(key, value string) Envelope[T] {
+	headers := make(map[string]string, len(e.Headers)+1)
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+	headers[key] = value
+	e.Headers = headers
+	return e
+}
+
+// RequestReply implements request/response messaging on top of two PubSub instances:
+// one carrying requests and one carrying replies.
+type RequestReply[Req, Resp any] struct {
+	requests *PubSub[Envelope[Req]]  // Broker on which requests are published
+	replies  *PubSub[Envelope[Resp]] // Broker on which replies are published to inboxes
+}
+
+// NewRequestReply creates a request/reply layer over the given brokers.
+// The brokers remain usable directly, e.g. to observe requests on a topic.
+func NewRequestReply[Req, Resp any](requests *PubSub[Envelope[Req]], replies *PubSub[Envelope[Resp]]) *RequestReply[Req, Resp] {
+	return &RequestReply[Req, Resp]{
+		requests: requests,
+		replies:  replies,
+	}
+}
+
+// Request publishes req on topic and waits for the first reply.
+// Returns ctx.Err() if no reply arrives before the context is done.
+func (rr *RequestReply[Req, Resp]) Request(ctx context.Context, topic string, req Req) (Resp, error) {
+	var zero Resp
+
+	inbox, ch := rr.openInbox()
+	defer rr.replies.Unsubscribe(inbox, ch)
+
+	id, err := rr.publishRequest(topic, inbox, req)
+	if err != nil {
+		return zero, err
+	}
+
+	for {
+		select {
+		case reply, ok := <-ch:
+			if !ok {
+				return zero, ErrClosed
+			}
+			if reply.CorrelationID != id {
+				continue // Stale or foreign reply; keep waiting
+			}
+			if msg := reply.Header(HeaderError); msg != "" {
+				return zero, &ResponderError{Topic: topic, Message: msg}
+			}
+			return reply.Payload, nil
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// ScatterGather publishes req on topic and collects replies from every responder
+// until ctx is done or maxReplies replies have arrived (maxReplies <= 0 means no cap).
+// Reaching the context deadline ends the collection normally and is not reported as an error;
+// responder failures are joined into the returned error.
+func (rr *RequestReply[Req, Resp]) ScatterGather(ctx context.Context, topic string, req Req, maxReplies int) ([]Resp, error) {
+	inbox, ch := rr.openInbox()
+	defer rr.replies.Unsubscribe(inbox, ch)
+
+	id, err := rr.publishRequest(topic, inbox, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Resp
+	var errs []error
+	for maxReplies <= 0 || len(results)+len(errs) < maxReplies {
+		select {
+		case reply, ok := <-ch:
+			if !ok {
+				return results, errors.Join(append(errs, ErrClosed)...)
+			}
+			if reply.CorrelationID != id {
+				continue
+			}
+			if msg := reply.Header(HeaderError); msg != "" {
+				errs = append(errs, &ResponderError{Topic: topic, Message: msg})
+				continue
+			}
+			results = append(results, reply.Payload)
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				errs = append(errs, ctx.Err())
+			}
+			return results, errors.Join(errs...)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Respond registers handler as a responder for requests on topic. Requests are handled by Handle,
+// so opts bound the workers and report failures; a panicking handler is recovered and reported
+// like any other failure. The result is published to the request's reply inbox, and a reply that
+// cannot be published counts as a failed invocation. Stop the returned Handler to unregister.
+func (rr *RequestReply[Req, Resp]) Respond(topic string, handler func(ctx context.Context, req Req) (Resp, error), opts ...HandleOption) *Handler[Envelope[Req]] {
+	return rr.requests.Handle(topic, func(ctx context.Context, req Envelope[Req]) error {
+		if req.ReplyTo == "" {
+			return nil // Not a request; nothing to reply to
+		}
+		resp, err := handler(ctx, req.Payload)
+		reply := ContinueTrace(req, Envelope[Resp]{
+			ID:            newID(),
+			CorrelationID: req.ID,
+			Payload:       resp,
+		})
+		if err != nil {
+			reply = reply.WithHeader(HeaderError, err.Error())
+		}
+		if err := rr.replies.Publish(req.ReplyTo, reply); err != nil {
+			return fmt.Errorf("pubsub: reply to %q: %w", req.ReplyTo, err)
+		}
+		return nil
+	}, opts...)
+}
+
+// openInbox subscribes to a freshly generated reply topic
+func (rr *RequestReply[Req, Resp]) openInbox() (string, chan Envelope[Resp]) {
+	inbox := inboxPrefix + newID()
+	return inbox, rr.replies.Subscribe(inbox)
+}
+
+// publishRequest publishes req on topic with inbox as its reply address and returns the request ID
+func (rr *RequestReply[Req, Resp]) publishRequest(topic, inbox string, req Req) (string, error) {
+	id := newID()
+	err := rr.requests.Publish(topic, Envelope[Req]{
+		ID:      id,
+		ReplyTo: inbox,
+		Payload: req,
+	})
+	return id, err
+}
+
+// ResponderError reports a failure returned by a responder's handler.
+type ResponderError struct {
+	Topic   string // Topic the request was published on
+	Message string // Error text reported by the responder
+}
+
+func (e *ResponderError) Error() string {
+	return fmt.Sprintf("pubsub: responder on %q failed: %s", e.Topic, e.Message)
+}
+
+// newID returns a random 128-bit identifier encoded as hex
+func newID() string {
+	var b [16]byte
+	randomBytes(b[:])
+	return hex.EncodeToString(b[:])
+}
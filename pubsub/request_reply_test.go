@@ -0,0 +1,131 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestReply(t *testing.T) {
+	rr := NewRequestReply(NewPubSub[Envelope[string]](), NewPubSub[Envelope[string]]())
+
+	h := rr.Respond("upper", func(ctx context.Context, req string) (string, error) {
+		if req == "" {
+			return "", errors.New("empty request")
+		}
+		return strings.ToUpper(req), nil
+	})
+	defer h.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Successful round trip
+	resp, err := rr.Request(ctx, "upper", "hello")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp != "HELLO" {
+		t.Errorf("Unexpected reply: got %q, expected %q", resp, "HELLO")
+	}
+
+	// Responder errors are surfaced to the requester
+	_, err = rr.Request(ctx, "upper", "")
+	var respErr *ResponderError
+	if !errors.As(err, &respErr) || respErr.Message != "empty request" {
+		t.Errorf("Expected ResponderError, got %v", err)
+	}
+
+	// No responder on the topic: the request times out
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if _, err := rr.Request(short, "nobody-home", "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestScatterGather(t *testing.T) {
+	rr := NewRequestReply(NewPubSub[Envelope[int]](), NewPubSub[Envelope[string]]())
+
+	const numResponders = 3
+	for i := 0; i < numResponders; i++ {
+		id := i
+		h := rr.Respond("census", func(ctx context.Context, req int) (string, error) {
+			return fmt.Sprintf("responder %d saw %d", id, req), nil
+		})
+		defer h.Stop()
+	}
+
+	// Collect until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	replies, err := rr.ScatterGather(ctx, "census", 42, 0)
+	if err != nil {
+		t.Fatalf("ScatterGather failed: %v", err)
+	}
+	if len(replies) != numResponders {
+		t.Errorf("Expected %d replies, got %d: %v", numResponders, len(replies), replies)
+	}
+
+	// Stop early once enough replies have arrived
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel2()
+	start := time.Now()
+	replies, err = rr.ScatterGather(ctx2, "census", 7, 2)
+	if err != nil {
+		t.Fatalf("ScatterGather failed: %v", err)
+	}
+	if len(replies) != 2 {
+		t.Errorf("Expected 2 replies, got %d", len(replies))
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("ScatterGather did not return after reaching maxReplies")
+	}
+}
+
+func TestRespondReportsPanicsAndUndeliverableReplies(t *testing.T) {
+	replies := NewPubSub[Envelope[string]]()
+	replies.Use(func(next PublishFunc[Envelope[string]]) PublishFunc[Envelope[string]] {
+		return func(topic string, message Envelope[string]) error {
+			if message.Payload == "reject" {
+				return errors.New("rejected by middleware")
+			}
+			return next(topic, message)
+		}
+	})
+	rr := NewRequestReply(NewPubSub[Envelope[string]](), replies)
+
+	failures := make(chan error, 2)
+	h := rr.Respond("echo", func(ctx context.Context, req string) (string, error) {
+		if req == "panic" {
+			panic("bad request")
+		}
+		return req, nil
+	}, WithErrorHandler(func(topic string, err error) { failures <- err }))
+	defer h.Stop()
+
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rr.Request(short, "echo", "panic")  // The responder keeps running
+	rr.Request(short, "echo", "reject") // The reply is rejected on its way back
+
+	var panicErr *PanicError
+	if err := <-failures; !errors.As(err, &panicErr) {
+		t.Errorf("Expected the panic to be reported, got %v", err)
+	}
+	if err := <-failures; err == nil || !strings.Contains(err.Error(), "rejected by middleware") {
+		t.Errorf("Expected the rejected reply to be reported, got %v", err)
+	}
+
+	ctx, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if resp, err := rr.Request(ctx, "echo", "still here"); err != nil || resp != "still here" {
+		t.Errorf("Expected the responder to keep serving, got %q, %v", resp, err)
+	}
+	if stats := h.Stats(); stats.Panicked != 1 || stats.Failed != 2 {
+		t.Errorf("Expected 1 panic and 2 failures, got %+v", stats)
+	}
+}
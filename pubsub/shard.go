@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultShards is the number of topic shards used when WithShards is not given.
+const DefaultShards = 32
+
+// WithShards sets how many independently locked buckets topics are spread across.
+// Writers on topics in different shards never contend. Values below 1 are treated as 1.
+func WithShards(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.shards = n
+	}
+}
+
+// shard is a bucket of topics with its own writer lock and copy-on-write topic map
+type shard[T any] struct {
+	topics atomic.Pointer[map[string]*topic[T]] // Immutable map of topics, replaced when a topic is added or removed
+	mu     sync.Mutex                           // Serializes writers on this shard's topics
+	_      [48]byte                             // Pad to a cache line so neighbouring shards do not share one
+}
+
+// shardFor returns the shard owning topic, chosen by an FNV-1a hash of its name
+func (ps *PubSub[T]) shardFor(topic string) *shard[T] {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(topic); i++ {
+		hash ^= uint32(topic[i])
+		hash *= prime32
+	}
+	return &ps.shards[hash%uint32(len(ps.shards))]
+}
+
+// lookup returns the topic entry without taking any lock
+func (s *shard[T]) lookup(name string) (*topic[T], bool) {
+	t, ok := (*s.topics.Load())[name]
+	return t, ok
+}
+
+// getOrCreateTopic returns the topic entry, adding it to a new copy of the shard's topic map if needed.
+// Must be called with s.mu held.
+func (s *shard[T]) getOrCreateTopic(name string) *topic[T] {
+	current := *s.topics.Load()
+	if t, ok := current[name]; ok {
+		return t
+	}
+
+	t := &topic[T]{}
+	t.subscribers.Store(&[]*subscriber[T]{})
+
+	next := make(map[string]*topic[T], len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[name] = t
+	s.topics.Store(&next)
+	return t
+}
+
+// removeTopic publishes a copy of the shard's topic map without name.
+// Must be called with s.mu held.
+func (s *shard[T]) removeTopic(name string) {
+	current := *s.topics.Load()
+	next := make(map[string]*topic[T], len(current))
+	for k, v := range current {
+		if k != name {
+			next[k] = v
+		}
+	}
+	s.topics.Store(&next)
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardsLockIndependently(t *testing.T) {
+	ps := NewPubSub[string](WithShards(8))
+
+	// Find two topics that hash to different shards
+	alerts := "alerts"
+	var news string
+	for i := 0; ; i++ {
+		news = fmt.Sprintf("news-%d", i)
+		if ps.shardFor(news) != ps.shardFor(alerts) {
+			break
+		}
+	}
+	newsSub := ps.Subscribe(news)
+
+	// Hold the alerts shard as if a writer were stuck on it
+	blocked := ps.shardFor(alerts)
+	blocked.mu.Lock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ch := ps.Subscribe(news)
+		ps.Publish(news, "breaking")
+		ps.Unsubscribe(news, ch)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Writers on another shard were blocked")
+	}
+	blocked.mu.Unlock()
+
+	if got := <-newsSub; got != "breaking" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	ps.Shutdown()
+}
+
+func TestWithShardsClampsToOne(t *testing.T) {
+	ps := NewPubSub[int](WithShards(0))
+	if len(ps.shards) != 1 {
+		t.Fatalf("Expected 1 shard, got %d", len(ps.shards))
+	}
+	ch := ps.Subscribe("only")
+	ps.Publish("only", 1)
+	if got := <-ch; got != 1 {
+		t.Errorf("Unexpected message %d", got)
+	}
+	ps.Shutdown()
+}
+
+// BenchmarkShardedTopics runs a mixed workload (90% Publish, 10% Subscribe/Unsubscribe)
+// over a matrix of shard counts, topic counts and goroutines per CPU.
+func BenchmarkShardedTopics(b *testing.B) {
+	for _, shards := range []int{1, DefaultShards} {
+		for _, numTopics := range []int{1, 64, 1024} {
+			for _, parallelism := range []int{1, 8} {
+				name := fmt.Sprintf("shards=%d/topics=%d/goroutines-per-cpu=%d", shards, numTopics, parallelism)
+				b.Run(name, func(b *testing.B) {
+					benchmarkShardedTopics(b, shards, numTopics, parallelism)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkShardedTopics(b *testing.B, shards, numTopics, parallelism int) {
+	ps := NewPubSub[int](WithShards(shards))
+	topics := make([]string, numTopics)
+	for i := range topics {
+		topics[i] = fmt.Sprintf("topic-%d", i)
+		ch := ps.Subscribe(topics[i])
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+
+	var seed atomic.Uint64
+	b.SetParallelism(parallelism)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rng := seed.Add(1) * 0x9E3779B97F4A7C15 // Per-goroutine xorshift state
+		for i := 0; pb.Next(); i++ {
+			rng ^= rng << 13
+			rng ^= rng >> 7
+			rng ^= rng << 17
+			topic := topics[rng%uint64(numTopics)]
+			if i%10 == 0 {
+				ps.Unsubscribe(topic, ps.Subscribe(topic))
+				continue
+			}
+			ps.Publish(topic, i)
+		}
+	})
+	b.StopTimer()
+	ps.Shutdown()
+}
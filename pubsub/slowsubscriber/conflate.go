@@ -0,0 +1,101 @@
+package pubsub
+
+import "sync"
+
+// SubscribeConflated subscribes to topic with a buffer that holds at most one message per key.
+// A message whose key already has a pending message replaces it in place, so the consumer always
+// reads the latest value of each key, in the order in which the keys first became pending.
+// Messages are never dropped; memory is bounded by the number of distinct keys.
+//
+// A feeder goroutine hands messages to the consumer and may hold one message while the consumer is not reading.
+func (ps *PubSub[T]) SubscribeConflated(topic string, key func(T) string) chan T {
+	sub := &subscriber[T]{
+		ch:   make(chan T), // Unused; messages go to the conflater
+		out:  make(chan T),
+		quit: make(chan struct{}),
+		conflate: &conflater[T]{
+			key:     key,
+			pending: make(map[string]T),
+			notify:  make(chan struct{}, 1),
+		},
+	}
+	go sub.feedConflated()
+	ps.add(topic, sub)
+	return sub.out
+}
+
+// feedConflated forwards the conflater's messages to out until the subscription is closed
+func (s *subscriber[T]) feedConflated() {
+	defer close(s.out)
+
+	for {
+		message, ok := s.conflate.pop()
+		if !ok {
+			select {
+			case <-s.conflate.notify:
+				continue
+			case <-s.quit:
+				return
+			}
+		}
+		select {
+		case s.out <- message:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// conflater is a FIFO of keys, each with the latest message published for it
+type conflater[T any] struct {
+	mu      sync.Mutex
+	key     func(T) string
+	pending map[string]T  // Latest message of every pending key
+	order   []string      // Pending keys in arrival order
+	notify  chan struct{} // Signals the feeder that a key became pending (capacity 1)
+}
+
+// put stores the message, replacing the pending message with the same key if there is one.
+// It reports whether the key became pending.
+func (c *conflater[T]) put(message T) bool {
+	k := c.key(message)
+
+	c.mu.Lock()
+	_, exists := c.pending[k]
+	c.pending[k] = message
+	if exists {
+		c.mu.Unlock()
+		return false // Keeps the key's place in the queue
+	}
+	c.order = append(c.order, k)
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop removes the message of the oldest pending key. ok is false if nothing is pending.
+func (c *conflater[T]) pop() (message T, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.order) == 0 {
+		return message, false
+	}
+
+	k := c.order[0]
+	c.order[0] = "" // Release the key string held by the backing array
+	c.order = c.order[1:]
+	message = c.pending[k]
+	delete(c.pending, k)
+	return message, true
+}
+
+// len returns the number of pending keys
+func (c *conflater[T]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.order)
+}
@@ -0,0 +1,79 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+// quote is a price update for an instrument
+type quote struct {
+	Symbol string
+	Price  int
+}
+
+// conflaterOf returns the conflater of the topic's only subscriber
+func conflaterOf(ps *PubSub[quote], topic string) *conflater[quote] {
+	return (*(*ps.topics.Load())[topic].subscribers.Load())[0].conflate
+}
+
+func TestConflatedKeepsLatestPerKeyInArrivalOrder(t *testing.T) {
+	ps := NewPubSub[quote]()
+	ch := ps.SubscribeConflated("quotes", func(q quote) string { return q.Symbol })
+
+	// Let the feeder take a first message, so everything after it stays pending until the consumer reads
+	ps.Publish("quotes", quote{"HOLD", 0})
+	deadline := time.Now().Add(time.Second)
+	for conflaterOf(ps, "quotes").len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Feeder did not take the first message")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, q := range []quote{{"AAPL", 1}, {"MSFT", 1}, {"AAPL", 2}, {"GOOG", 1}, {"MSFT", 2}, {"AAPL", 3}} {
+		ps.Publish("quotes", q)
+	}
+
+	want := []quote{{"HOLD", 0}, {"AAPL", 3}, {"MSFT", 2}, {"GOOG", 1}}
+	for i, w := range want {
+		if got := <-ch; got != w {
+			t.Errorf("Message %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+
+	// A key that was delivered starts a new place at the back of the queue
+	ps.Publish("quotes", quote{"AAPL", 4})
+	if got := <-ch; got != (quote{"AAPL", 4}) {
+		t.Errorf("Expected the new AAPL quote, got %+v", got)
+	}
+
+	ps.Unsubscribe("quotes", ch)
+	if _, ok := <-ch; ok {
+		t.Error("Expected the channel to be closed")
+	}
+}
+
+func TestConflatedNeverDrops(t *testing.T) {
+	ps := NewPubSub[quote](WithEviction(EvictionPolicy{MaxDrops: 1}))
+	ch := ps.SubscribeConflated("quotes", func(q quote) string { return q.Symbol })
+
+	// Far more updates than a channel buffer holds, over a few keys
+	symbols := []string{"AAPL", "MSFT", "GOOG"}
+	for i := 0; i < 1000; i++ {
+		ps.Publish("quotes", quote{symbols[i%len(symbols)], i})
+	}
+	if pending := conflaterOf(ps, "quotes").len(); pending > len(symbols) {
+		t.Errorf("Expected at most %d pending keys, got %d", len(symbols), pending)
+	}
+
+	// Read until the latest value of every key has arrived
+	latest := map[string]int{}
+	for len(latest) < len(symbols) || latest["AAPL"] != 999 || latest["MSFT"] != 997 || latest["GOOG"] != 998 {
+		q := <-ch
+		latest[q.Symbol] = q.Price
+	}
+	if err := ps.Err(ch); err != nil {
+		t.Errorf("Conflated subscriber was evicted: %v", err)
+	}
+	ps.Shutdown()
+}
@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEvicted matches every *Eviction with errors.Is.
+var ErrEvicted = errors.New("slowsubscriber: subscriber evicted")
+
+// EvictionPolicy decides when a slow subscriber is unsubscribed. A zero field disables its trigger;
+// the policy is checked whenever a message is published to the subscriber's topic.
+type EvictionPolicy struct {
+	MaxDrops     uint64        // Evict once this many messages have been dropped for the subscriber
+	SaturatedFor time.Duration // Evict once the subscriber's buffer has stayed full for this long
+}
+
+// EvictionReason tells which trigger of the EvictionPolicy evicted a subscriber.
+type EvictionReason int
+
+const (
+	// EvictedForDrops means the subscriber reached MaxDrops.
+	EvictedForDrops EvictionReason = iota + 1
+	// EvictedForSaturation means the subscriber's buffer stayed full for SaturatedFor.
+	EvictedForSaturation
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedForDrops:
+		return "too many dropped messages"
+	case EvictedForSaturation:
+		return "buffer saturated"
+	}
+	return "unknown"
+}
+
+// Eviction describes a subscriber that was evicted. It is returned by Err for the evicted channel
+// and published on EvictionTopic of the System broker.
+type Eviction struct {
+	Topic  string         // Topic the subscriber was evicted from
+	Reason EvictionReason // Trigger that evicted it
+	Drops  uint64         // Messages dropped for the subscriber before it was evicted
+	At     time.Time      // When it was evicted
+}
+
+func (e *Eviction) Error() string {
+	return fmt.Sprintf("slowsubscriber: subscriber evicted from %q: %v after %d dropped messages", e.Topic, e.Reason, e.Drops)
+}
+
+// Unwrap lets errors.Is(err, ErrEvicted) match.
+func (e *Eviction) Unwrap() error {
+	return ErrEvicted
+}
+
+// Err returns the *Eviction that explains why ch was closed, or nil if it was not evicted.
+// The reason is kept until ch is passed to Unsubscribe.
+func (ps *PubSub[T]) Err(ch chan T) error {
+	if e, ok := ps.evictions.Load(ch); ok {
+		return e.(*Eviction)
+	}
+	return nil
+}
+
+// check reports whether the subscriber must be evicted under policy after a send at now.
+// Must be called with s.mu held.
+func (s *subscriber[T]) check(policy *EvictionPolicy, delivered bool, now time.Time) *Eviction {
+	switch {
+	case delivered && len(s.ch) < cap(s.ch):
+		s.saturatedSince = time.Time{}
+	case delivered:
+		s.saturatedSince = now // The buffer had room until this message
+	case s.saturatedSince.IsZero():
+		s.saturatedSince = now
+	}
+
+	switch {
+	case policy.MaxDrops > 0 && s.drops >= policy.MaxDrops:
+		return &Eviction{Reason: EvictedForDrops, Drops: s.drops, At: now}
+	case policy.SaturatedFor > 0 && !s.saturatedSince.IsZero() && now.Sub(s.saturatedSince) >= policy.SaturatedFor:
+		return &Eviction{Reason: EvictedForSaturation, Drops: s.drops, At: now}
+	}
+	return nil
+}
+
+// evict unsubscribes sub, records why and publishes the eviction event.
+// It is a no-op if sub was already removed, for example by a concurrent publisher evicting it first.
+func (ps *PubSub[T]) evict(topic string, sub *subscriber[T], e *Eviction) {
+	ps.mu.Lock()
+	removed := ps.remove(topic, func(s *subscriber[T]) bool { return s == sub })
+	if removed == nil {
+		ps.mu.Unlock()
+		return
+	}
+	e.Topic = topic
+	ps.evictions.Store(sub.out, e) // Stored before closing, so the consumer finds it once the channel is closed
+	sub.close()
+	ps.mu.Unlock()
+
+	if sys := ps.system.Load(); sys != nil {
+		sys.Publish(EvictionTopic, e)
+	}
+}
@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+)
+
+func TestEvictAfterMaxDrops(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk), WithEviction(EvictionPolicy{MaxDrops: 5}))
+	events := ps.System().Subscribe(EvictionTopic)
+
+	slow := ps.Subscribe("quotes")
+	fast := ps.Subscribe("quotes")
+	for i := 0; i < 105; i++ {
+		ps.Publish("quotes", i)
+		<-fast
+	}
+
+	// The slow subscriber keeps what was buffered, then finds its channel closed
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != 100 {
+		t.Errorf("Expected the 100 buffered messages, got %d", received)
+	}
+
+	err := ps.Err(slow)
+	var e *Eviction
+	if !errors.As(err, &e) || !errors.Is(err, ErrEvicted) {
+		t.Fatalf("Expected an *Eviction matching ErrEvicted, got %v", err)
+	}
+	want := Eviction{Topic: "quotes", Reason: EvictedForDrops, Drops: 5, At: time.Unix(0, 0)}
+	if *e != want {
+		t.Errorf("Expected %+v, got %+v", want, *e)
+	}
+
+	select {
+	case event := <-events:
+		if event != e {
+			t.Errorf("Expected the eviction event to be %+v, got %+v", e, event)
+		}
+	default:
+		t.Error("No eviction event was published")
+	}
+
+	// The fast subscriber is still subscribed
+	ps.Publish("quotes", 105)
+	if msg := <-fast; msg != 105 {
+		t.Errorf("Fast subscriber received %d, expected 105", msg)
+	}
+	if err := ps.Err(fast); err != nil {
+		t.Errorf("Fast subscriber was evicted: %v", err)
+	}
+
+	ps.Unsubscribe("quotes", slow)
+	if err := ps.Err(slow); err != nil {
+		t.Errorf("Expected Unsubscribe to forget the eviction, got %v", err)
+	}
+}
+
+func TestEvictAfterSustainedSaturation(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk), WithEviction(EvictionPolicy{SaturatedFor: 10 * time.Second}))
+
+	ch := ps.Subscribe("quotes")
+	for i := 0; i < 100; i++ {
+		ps.Publish("quotes", i) // The buffer is full from now on
+	}
+
+	clk.Advance(5 * time.Second)
+	ps.Publish("quotes", 100)
+	if err := ps.Err(ch); err != nil {
+		t.Fatalf("Evicted before the buffer was saturated for 10s: %v", err)
+	}
+
+	clk.Advance(5 * time.Second)
+	ps.Publish("quotes", 101)
+	var e *Eviction
+	if !errors.As(ps.Err(ch), &e) || e.Reason != EvictedForSaturation || e.Drops != 2 {
+		t.Fatalf("Expected an eviction for saturation after 2 drops, got %v", ps.Err(ch))
+	}
+	if !e.At.Equal(time.Unix(10, 0)) {
+		t.Errorf("Expected the eviction at %v, got %v", time.Unix(10, 0), e.At)
+	}
+}
+
+func TestSaturationResetsWhenConsumerCatchesUp(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk), WithEviction(EvictionPolicy{SaturatedFor: 10 * time.Second}))
+
+	ch := ps.Subscribe("quotes")
+	for i := 0; i < 100; i++ {
+		ps.Publish("quotes", i)
+	}
+
+	// Reading makes room, so the buffer only fills up again at the next publish
+	clk.Advance(9 * time.Second)
+	<-ch
+	ps.Publish("quotes", 100)
+
+	clk.Advance(9 * time.Second)
+	ps.Publish("quotes", 101)
+	if err := ps.Err(ch); err != nil {
+		t.Errorf("Evicted although the buffer had room 9s ago: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package pubsub
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultLagSmoothing is the EWMA weight of the newest consumption rate sample when LagMonitor.Smoothing is zero.
+const DefaultLagSmoothing = 0.3
+
+// LagMonitor configures the sampling of subscription lag and the thresholds that raise alerts.
+// A zero threshold disables its alert.
+type LagMonitor struct {
+	Interval  time.Duration // How often every subscription is sampled; required
+	MaxDepth  int           // Alert when more messages than this are pending
+	MaxAge    time.Duration // Alert when the oldest pending message has waited longer than this
+	MinRate   float64       // Alert when messages are pending and the consumption rate is below this many per second
+	Smoothing float64       // Weight of the newest sample in the rate EWMA, in (0, 1]; DefaultLagSmoothing if zero
+}
+
+// WithLagMonitor samples every subscription each Interval and publishes a *LagAlert on LagAlertTopic
+// of the System broker whenever a subscription crosses a threshold, and again once it is back within it.
+// Sampling runs on a goroutine driven by the broker's clock until Shutdown.
+// It panics if Interval is not positive.
+func WithLagMonitor(cfg LagMonitor) Option {
+	return func(o *options) {
+		if cfg.Interval <= 0 {
+			panic("slowsubscriber: lag monitor interval must be positive")
+		}
+		if cfg.Smoothing == 0 {
+			cfg.Smoothing = DefaultLagSmoothing
+		}
+		o.lagMonitor = &cfg
+	}
+}
+
+// Lag describes how far a subscription is behind.
+type Lag struct {
+	Topic     string        // Topic of the subscription
+	ID        uint64        // Identifies the subscription among those of the broker
+	Depth     int           // Messages waiting for the consumer, including spilled ones
+	Capacity  int           // Size of the in-memory buffer; 0 for conflated subscriptions, which are bounded by their keys
+	OldestAge time.Duration // How long the oldest pending message has waited
+	Rate      float64       // EWMA of messages consumed per second
+}
+
+// LagReason tells which threshold of the LagMonitor a LagAlert is about.
+type LagReason int
+
+const (
+	// LagDepth is raised when Depth exceeds MaxDepth.
+	LagDepth LagReason = iota
+	// LagAge is raised when OldestAge exceeds MaxAge.
+	LagAge
+	// LagRate is raised when messages are pending and Rate is below MinRate.
+	LagRate
+	numLagReasons
+)
+
+func (r LagReason) String() string {
+	switch r {
+	case LagDepth:
+		return "queue depth"
+	case LagAge:
+		return "oldest message age"
+	case LagRate:
+		return "consumption rate"
+	}
+	return "unknown"
+}
+
+// LagAlert is published on LagAlertTopic when a subscription crosses a LagMonitor threshold,
+// and with Resolved set once it is back within the threshold.
+type LagAlert struct {
+	Lag                // Measurements at the time of the alert
+	Reason   LagReason // Threshold that was crossed
+	Resolved bool      // Set when the subscription is back within the threshold
+	At       time.Time // When the alert was raised
+}
+
+func (a *LagAlert) String() string {
+	state := "exceeded"
+	if a.Resolved {
+		state = "resolved"
+	}
+	return fmt.Sprintf("subscription %d on %q: %v %s (depth %d, oldest %v, %.2f msg/s)",
+		a.ID, a.Topic, a.Reason, state, a.Depth, a.OldestAge, a.Rate)
+}
+
+// Lag returns how far every subscription is behind, sorted by topic and ID.
+// OldestAge and Rate are only measured with WithLagMonitor.
+func (ps *PubSub[T]) Lag() []Lag {
+	now := ps.clock.Now()
+	var lags []Lag
+	for name, t := range *ps.topics.Load() {
+		for _, sub := range *t.subscribers.Load() {
+			lags = append(lags, sub.lagAt(name, now))
+		}
+	}
+	slices.SortFunc(lags, func(a, b Lag) int {
+		return cmp.Or(cmp.Compare(a.Topic, b.Topic), cmp.Compare(a.ID, b.ID))
+	})
+	return lags
+}
+
+// monitor samples every subscription each interval until stop is closed
+func (ps *PubSub[T]) monitor(cfg *LagMonitor, stop <-chan struct{}) {
+	ticker := ps.clock.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		}
+
+		now := ps.clock.Now()
+		for name, t := range *ps.topics.Load() {
+			for _, sub := range *t.subscribers.Load() {
+				for _, alert := range sub.sample(name, cfg, now) {
+					if sys := ps.system.Load(); sys != nil {
+						sys.Publish(LagAlertTopic, alert)
+					}
+				}
+			}
+		}
+	}
+}
+
+// arrival is a run of messages accepted for a subscriber within one grain of time
+type arrival struct {
+	at time.Time // When the first message of the run was accepted
+	n  uint64
+}
+
+// lagTracker measures a subscriber's lag. It is guarded by the subscriber's mu.
+type lagTracker struct {
+	grain    time.Duration // Runs of arrivals closer together than this are merged
+	arrivals []arrival     // Accepted messages not yet known to be consumed, oldest first
+	accepted uint64        // Messages that became pending
+	consumed uint64        // Messages known to have been taken by the consumer
+
+	rate         float64   // EWMA of messages consumed per second
+	sampled      bool      // Set once rate holds a sample
+	lastConsumed uint64    // consumed at the previous sample
+	lastSample   time.Time // Time of the previous sample
+
+	alerting [numLagReasons]bool // Thresholds currently exceeded
+}
+
+func newLagTracker(cfg *LagMonitor, now time.Time) *lagTracker {
+	return &lagTracker{grain: cfg.Interval / 10, lastSample: now}
+}
+
+// arrive records a message that became pending at now
+func (l *lagTracker) arrive(now time.Time) {
+	l.accepted++
+	if n := len(l.arrivals); n > 0 && now.Sub(l.arrivals[n-1].at) < l.grain {
+		l.arrivals[n-1].n++
+		return
+	}
+	l.arrivals = append(l.arrivals, arrival{at: now, n: 1})
+}
+
+// settle counts as consumed every accepted message that is no longer pending and returns the age of the oldest pending one.
+// Buffers are FIFO, so the consumed messages are the oldest arrivals.
+func (l *lagTracker) settle(depth int, now time.Time) time.Duration {
+	consumed := l.accepted - uint64(depth)
+	for remove := consumed - l.consumed; remove > 0 && len(l.arrivals) > 0; {
+		first := &l.arrivals[0]
+		if first.n > remove {
+			first.n -= remove
+			break
+		}
+		remove -= first.n
+		l.arrivals[0] = arrival{}
+		l.arrivals = l.arrivals[1:]
+	}
+	l.consumed = consumed
+
+	if depth == 0 || len(l.arrivals) == 0 {
+		return 0
+	}
+	return now.Sub(l.arrivals[0].at)
+}
+
+// depth returns the number of messages waiting for the consumer. Must be called with s.mu held.
+func (s *subscriber[T]) depth() int {
+	switch {
+	case s.conflate != nil:
+		return s.conflate.len()
+	case s.spill != nil:
+		return len(s.ch) + s.spill.len()
+	}
+	return len(s.ch)
+}
+
+// lagAt measures the subscriber's lag without updating the rate
+func (s *subscriber[T]) lagAt(topic string, now time.Time) Lag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lag := Lag{Topic: topic, ID: s.id, Depth: s.depth(), Capacity: cap(s.ch)}
+	if s.lag != nil {
+		lag.OldestAge = s.lag.settle(lag.Depth, now)
+		lag.Rate = s.lag.rate
+	}
+	return lag
+}
+
+// sample measures the subscriber's lag, updates the rate EWMA and returns the alerts for thresholds crossed since the last sample
+func (s *subscriber[T]) sample(topic string, cfg *LagMonitor, now time.Time) []*LagAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.lag == nil {
+		return nil
+	}
+
+	l := s.lag
+	lag := Lag{Topic: topic, ID: s.id, Depth: s.depth(), Capacity: cap(s.ch)}
+	lag.OldestAge = l.settle(lag.Depth, now)
+
+	if elapsed := now.Sub(l.lastSample).Seconds(); elapsed > 0 {
+		current := float64(l.consumed-l.lastConsumed) / elapsed
+		if l.sampled {
+			l.rate = cfg.Smoothing*current + (1-cfg.Smoothing)*l.rate
+		} else {
+			l.rate, l.sampled = current, true
+		}
+		l.lastConsumed, l.lastSample = l.consumed, now
+	}
+	lag.Rate = l.rate
+
+	exceeded := [numLagReasons]bool{
+		LagDepth: cfg.MaxDepth > 0 && lag.Depth > cfg.MaxDepth,
+		LagAge:   cfg.MaxAge > 0 && lag.OldestAge > cfg.MaxAge,
+		LagRate:  cfg.MinRate > 0 && lag.Depth > 0 && lag.Rate < cfg.MinRate,
+	}
+	var alerts []*LagAlert
+	for reason := range numLagReasons {
+		if exceeded[reason] != l.alerting[reason] {
+			l.alerting[reason] = exceeded[reason]
+			alerts = append(alerts, &LagAlert{Lag: lag, Reason: reason, Resolved: !exceeded[reason], At: now})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock/fakeclock"
+)
+
+// nextAlert returns the next lag alert published on the System broker
+func nextAlert(t *testing.T, alerts chan any) *LagAlert {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		return alert.(*LagAlert)
+	case <-time.After(time.Second):
+		t.Fatal("No lag alert was published")
+		return nil
+	}
+}
+
+func TestLagMonitorRaisesAndResolvesAlerts(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk), WithLagMonitor(LagMonitor{
+		Interval: time.Second,
+		MaxDepth: 10,
+		MaxAge:   5 * time.Second,
+		MinRate:  1,
+	}))
+	alerts := ps.System().Subscribe(LagAlertTopic)
+	ch := ps.Subscribe("quotes")
+
+	for i := 0; i < 20; i++ {
+		ps.Publish("quotes", i)
+	}
+	clk.BlockUntil(1) // The monitor's ticker
+	clk.Advance(time.Second)
+
+	// Too many pending messages and none consumed
+	want := Lag{Topic: "quotes", ID: 1, Depth: 20, Capacity: 100, OldestAge: time.Second}
+	for _, reason := range []LagReason{LagDepth, LagRate} {
+		alert := nextAlert(t, alerts)
+		if alert.Reason != reason || alert.Resolved || alert.Lag != want || !alert.At.Equal(time.Unix(1, 0)) {
+			t.Errorf("Expected a %v alert with %+v, got %v", reason, want, alert)
+		}
+	}
+	if lags := ps.Lag(); len(lags) != 1 || lags[0] != want {
+		t.Errorf("Expected Lag to report %+v, got %+v", want, lags)
+	}
+
+	// Consuming 15 messages in 5s brings the depth down, but the oldest ones have waited 6s
+	for i := 0; i < 15; i++ {
+		<-ch
+	}
+	clk.Advance(5 * time.Second)
+	if alert := nextAlert(t, alerts); alert.Reason != LagDepth || !alert.Resolved || alert.Depth != 5 {
+		t.Errorf("Expected the depth alert to be resolved at depth 5, got %v", alert)
+	}
+	if alert := nextAlert(t, alerts); alert.Reason != LagAge || alert.Resolved || alert.OldestAge != 6*time.Second {
+		t.Errorf("Expected an age alert at 6s, got %v", alert)
+	}
+	if rate := ps.Lag()[0].Rate; rate < 0.89 || rate > 0.91 {
+		t.Errorf("Expected a smoothed rate of 0.9 msg/s, got %v", rate)
+	}
+
+	// Once the consumer has caught up, the remaining alerts are resolved
+	for i := 0; i < 5; i++ {
+		<-ch
+	}
+	clk.Advance(time.Second)
+	for _, reason := range []LagReason{LagAge, LagRate} {
+		if alert := nextAlert(t, alerts); alert.Reason != reason || !alert.Resolved {
+			t.Errorf("Expected the %v alert to be resolved, got %v", reason, alert)
+		}
+	}
+
+	ps.Shutdown()
+	deadline := time.Now().Add(time.Second)
+	for clk.Waiters() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Lag monitor did not stop on Shutdown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLagTracksAgeAcrossArrivals(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[int](WithClock(clk), WithLagMonitor(LagMonitor{Interval: time.Minute}))
+	ch := ps.Subscribe("quotes")
+
+	ps.Publish("quotes", 1)
+	clk.Advance(10 * time.Second)
+	ps.Publish("quotes", 2)
+	clk.Advance(10 * time.Second)
+
+	if lag := ps.Lag()[0]; lag.Depth != 2 || lag.OldestAge != 20*time.Second {
+		t.Errorf("Expected 2 pending messages, the oldest 20s old, got %+v", lag)
+	}
+	<-ch
+	if lag := ps.Lag()[0]; lag.Depth != 1 || lag.OldestAge != 10*time.Second {
+		t.Errorf("Expected 1 pending message, 10s old, got %+v", lag)
+	}
+	ps.Shutdown()
+}
+
+func TestLagOfConflatedSubscription(t *testing.T) {
+	clk := fakeclock.New(time.Unix(0, 0))
+	ps := NewPubSub[quote](WithClock(clk), WithLagMonitor(LagMonitor{Interval: time.Minute}))
+	ch := ps.SubscribeConflated("quotes", func(q quote) string { return q.Symbol })
+
+	// Let the feeder hold a first message so the rest stay pending
+	ps.Publish("quotes", quote{"HOLD", 0})
+	deadline := time.Now().Add(time.Second)
+	for conflaterOf(ps, "quotes").len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Feeder did not take the first message")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("quotes", quote{"AAPL", 1})
+	clk.Advance(5 * time.Second)
+	ps.Publish("quotes", quote{"AAPL", 2}) // Replaces the pending AAPL quote; its age is kept
+	ps.Publish("quotes", quote{"MSFT", 1})
+
+	if lag := ps.Lag()[0]; lag.Depth != 2 || lag.Capacity != 0 || lag.OldestAge != 5*time.Second {
+		t.Errorf("Expected 2 pending keys, the oldest 5s old, got %+v", lag)
+	}
+	<-ch
+	ps.Shutdown()
+}
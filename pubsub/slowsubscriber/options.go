@@ -0,0 +1,28 @@
+package pubsub
+
+import "github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
+
+// Option configures a PubSub created by NewPubSub.
+type Option func(*options)
+
+// options holds the settings collected from Options
+type options struct {
+	clock      clock.Clock     // Source of time for saturation tracking and events
+	eviction   *EvictionPolicy // When slow subscribers are evicted; nil to never evict
+	lagMonitor *LagMonitor     // Lag sampling and alert thresholds; nil if disabled
+}
+
+// WithClock sets the time source of the broker. Defaults to clock.Real().
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithEviction evicts subscribers that fall too far behind, see EvictionPolicy.
+// By default messages are dropped for a slow subscriber forever.
+func WithEviction(policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.eviction = &policy
+	}
+}
@@ -0,0 +1,23 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendFromStaleSnapshotAfterUnsubscribe(t *testing.T) {
+	ps := NewPubSub[int](WithEviction(EvictionPolicy{MaxDrops: 1}))
+	ch := ps.Subscribe("topic")
+	stale := *(*ps.topics.Load())["topic"].subscribers.Load() // As held by a publisher mid-fan-out
+
+	ps.Unsubscribe("topic", ch)
+	if e := stale[0].send(1, ps.eviction, time.Now()); e != nil || stale[0].drops != 0 {
+		t.Errorf("Expected a send to an unsubscribed subscriber to be ignored, got eviction %v and %d drops", e, stale[0].drops)
+	}
+	if msg, ok := <-ch; ok {
+		t.Errorf("Expected the channel to be closed without the message, got %d", msg)
+	}
+	if err := ps.Err(ch); err != nil {
+		t.Errorf("Expected no eviction for an unsubscribed channel, got %v", err)
+	}
+}
@@ -3,74 +3,246 @@ package pubsub
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ganeshskudva/Golang-Concurrency-Playground/pubsub/clock"
 )
 
 // PubSub manages publishers and subscribers for any message type
+// The subscriber registry is copy-on-write so Publish takes no broker locks
 type PubSub[T any] struct {
-	subscribers map[string]map[chan T]struct{} // Map of topics to a set of subscriber channels
-	mu          sync.RWMutex                   // Read-Write lock to manage concurrent access
+	topics atomic.Pointer[map[string]*topic[T]] // Immutable map of topics, replaced when a topic is added or removed
+	mu     sync.Mutex                           // Serializes Subscribe, Unsubscribe, Shutdown and evictions
+	clock  clock.Clock                          // Source of time for saturation tracking and events
+
+	eviction  *EvictionPolicy             // When slow subscribers are evicted; nil to never evict
+	evictions sync.Map                    // Channel of an evicted subscriber to its *Eviction
+	system    atomic.Pointer[PubSub[any]] // Broker for events about this one; nil until System is called
+
+	lagMonitor *LagMonitor   // Lag sampling and alert thresholds; nil if disabled
+	stop       chan struct{} // Closed by Shutdown to stop the lag monitor
+	stopOnce   sync.Once
+	nextID     atomic.Uint64 // Last subscription ID handed out
+}
+
+// topic holds the current immutable snapshot of a topic's subscribers
+type topic[T any] struct {
+	subscribers atomic.Pointer[[]*subscriber[T]] // Replaced, never modified in place
+}
+
+// subscriber guards a subscription channel so it is never sent on after being closed
+type subscriber[T any] struct {
+	ch       chan T         // Buffer that Publish sends into
+	out      chan T         // Channel handed to the consumer; the same as ch unless a feeder goroutine fills it
+	quit     chan struct{}  // Closed with ch to stop the feeder; nil if there is none
+	spill    *spillQueue[T] // Overflow queue on disk; nil unless subscribed with SubscribeSpill
+	conflate *conflater[T]  // Keyed buffer used instead of ch; nil unless subscribed with SubscribeConflated
+	mu       sync.Mutex     // Held while sending and while closing
+	closed   bool
+
+	id             uint64      // Identifies the subscription in Lag and LagAlert
+	drops          uint64      // Messages dropped because ch was full
+	saturatedSince time.Time   // When ch was last found full; zero while it has room
+	lag            *lagTracker // Measures how far the consumer is behind; nil without a lag monitor
 }
 
 // NewPubSub initializes a new PubSub instance for a specific type
-func NewPubSub[T any]() *PubSub[T] {
-	return &PubSub[T]{
-		subscribers: make(map[string]map[chan T]struct{}),
+func NewPubSub[T any](opts ...Option) *PubSub[T] {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ps := &PubSub[T]{clock: o.clock, eviction: o.eviction, lagMonitor: o.lagMonitor, stop: make(chan struct{})}
+	ps.topics.Store(&map[string]*topic[T]{})
+	if ps.lagMonitor != nil {
+		go ps.monitor(ps.lagMonitor, ps.stop)
 	}
+	return ps
 }
 
 // Subscribe adds a new subscriber to a specific topic
 func (ps *PubSub[T]) Subscribe(topic string) chan T {
 	ch := make(chan T, 100) // Buffered channel for slow subscriber handling
+	ps.add(topic, &subscriber[T]{ch: ch, out: ch})
+	return ch
+}
+
+// add registers a subscriber with a topic
+func (ps *PubSub[T]) add(topic string, sub *subscriber[T]) {
+	sub.id = ps.nextID.Add(1)
+	if ps.lagMonitor != nil {
+		sub.lag = newLagTracker(ps.lagMonitor, ps.clock.Now())
+	}
+
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	if ps.subscribers[topic] == nil {
-		ps.subscribers[topic] = make(map[chan T]struct{})
-	}
-	ps.subscribers[topic][ch] = struct{}{}
-	return ch
+	// Swap in a new snapshot containing the subscriber
+	t := ps.getOrCreateTopic(topic)
+	current := *t.subscribers.Load()
+	next := make([]*subscriber[T], len(current), len(current)+1)
+	copy(next, current)
+	next = append(next, sub)
+	t.subscribers.Store(&next)
 }
 
 // Publish sends a message to all subscribers of a topic
 // Handles slow subscribers by dropping messages if the buffer is full
 func (ps *PubSub[T]) Publish(topic string, message T) {
-	ps.mu.RLock()
-	defer ps.mu.RUnlock()
+	t, ok := (*ps.topics.Load())[topic]
+	if !ok {
+		return
+	}
 
-	for ch := range ps.subscribers[topic] {
+	var now time.Time
+	if ps.eviction != nil || ps.lagMonitor != nil {
+		now = ps.clock.Now()
+	}
+	for _, sub := range *t.subscribers.Load() {
+		if e := sub.send(message, ps.eviction, now); e != nil {
+			ps.evict(topic, sub, e)
+		}
+	}
+}
+
+// send delivers the message without blocking; it is a no-op once the subscriber is closed.
+// With an eviction policy, it returns a non-nil *Eviction if the subscriber must be evicted.
+func (s *subscriber[T]) send(message T, policy *EvictionPolicy, now time.Time) *Eviction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil // Unsubscribed after the snapshot was taken
+	}
+
+	delivered, arrived := true, true
+	if s.conflate != nil {
+		arrived = s.conflate.put(message) // Replaces the pending message with the same key
+	} else if s.spill != nil && s.spill.pending() {
+		delivered = s.spill.push(message) // Queue behind the messages already on disk
+	} else {
 		select {
-		case ch <- message: // Deliver message
-		default: // Drop message if channel is full
-			fmt.Printf("Dropping message for a slow subscriber: %v\n", message)
+		case s.ch <- message: // Deliver message
+		default:
+			if s.spill != nil {
+				delivered = s.spill.push(message) // Spill to disk if the buffer is full
+			} else { // Drop message if channel is full
+				delivered = false
+				fmt.Printf("Dropping message for a slow subscriber: %v\n", message)
+			}
 		}
 	}
+	if !delivered {
+		s.drops++
+	} else if arrived && s.lag != nil {
+		s.lag.arrive(now)
+	}
+
+	if policy == nil {
+		return nil
+	}
+	return s.check(policy, delivered, now)
+}
+
+// close closes the subscriber channel once no send is in progress
+func (s *subscriber[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch) // Close channel to signal subscriber
+	if s.quit != nil {
+		close(s.quit)
+	}
 }
 
 // Unsubscribe removes a subscriber from a specific topic
+// Unsubscribing an evicted channel forgets its eviction reason
 func (ps *PubSub[T]) Unsubscribe(topic string, ch chan T) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	if subscribers, ok := ps.subscribers[topic]; ok {
-		if _, exists := subscribers[ch]; exists {
-			delete(subscribers, ch)
-			close(ch) // Close channel to signal subscriber
-		}
-		if len(subscribers) == 0 {
-			delete(ps.subscribers, topic)
+	if removed := ps.remove(topic, func(sub *subscriber[T]) bool { return sub.out == ch }); removed != nil {
+		removed.close()
+	}
+	ps.evictions.Delete(ch)
+}
+
+// remove takes the first subscriber matching match out of the topic's snapshot and returns it, or nil if none matched
+// Must be called with ps.mu held
+func (ps *PubSub[T]) remove(topic string, match func(*subscriber[T]) bool) *subscriber[T] {
+	t, ok := (*ps.topics.Load())[topic]
+	if !ok {
+		return nil
+	}
+
+	current := *t.subscribers.Load()
+	next := make([]*subscriber[T], 0, len(current))
+	var removed *subscriber[T]
+	for _, sub := range current {
+		if removed == nil && match(sub) {
+			removed = sub
+			continue
 		}
+		next = append(next, sub)
+	}
+
+	if removed != nil {
+		t.subscribers.Store(&next)
 	}
+	if len(next) == 0 {
+		ps.removeTopic(topic)
+	}
+	return removed
 }
 
-// Shutdown gracefully shuts down the PubSub system by closing all channels
+// Shutdown gracefully shuts down the PubSub system by closing all channels, including those of the System broker,
+// and stops the lag monitor
 func (ps *PubSub[T]) Shutdown() {
 	ps.mu.Lock()
-	defer ps.mu.Unlock()
+	old := ps.topics.Swap(&map[string]*topic[T]{})
+	for _, t := range *old {
+		for _, sub := range *t.subscribers.Load() {
+			sub.close()
+		}
+	}
+	ps.mu.Unlock()
+
+	ps.stopOnce.Do(func() { close(ps.stop) })
+	if sys := ps.system.Load(); sys != nil {
+		sys.Shutdown()
+	}
+}
+
+// getOrCreateTopic returns the topic entry, adding it to a new copy of the topic map if needed
+// Must be called with ps.mu held
+func (ps *PubSub[T]) getOrCreateTopic(name string) *topic[T] {
+	current := *ps.topics.Load()
+	if t, ok := current[name]; ok {
+		return t
+	}
+
+	t := &topic[T]{}
+	t.subscribers.Store(&[]*subscriber[T]{})
+
+	next := make(map[string]*topic[T], len(current)+1)
+	for k, v := range current {
+		next[k] = v
+	}
+	next[name] = t
+	ps.topics.Store(&next)
+	return t
+}
 
-	for topic, subscribers := range ps.subscribers {
-		for ch := range subscribers {
-			close(ch)
+// removeTopic swaps in a copy of the topic map without name
+// Must be called with ps.mu held
+func (ps *PubSub[T]) removeTopic(name string) {
+	current := *ps.topics.Load()
+	next := make(map[string]*topic[T], len(current))
+	for k, v := range current {
+		if k != name {
+			next[k] = v
 		}
-		delete(ps.subscribers, topic)
 	}
+	ps.topics.Store(&next)
 }
@@ -2,45 +2,25 @@ package pubsub
 
 import (
 	"fmt"
-	"sync"
 	"testing"
-	"time"
 )
 
 func TestSlowSubscriberHandling(t *testing.T) {
 	ps := NewPubSub[string]()
 
-	// Subscriber 1: Fast subscriber
+	// Subscriber 1: Fast subscriber, reads every message as soon as it is published
 	fastSub := ps.Subscribe("news")
 	var fastMessages []string
-	fastWg := sync.WaitGroup{}
-	fastWg.Add(1)
 
-	go func() {
-		defer fastWg.Done()
-		for msg := range fastSub {
-			fastMessages = append(fastMessages, msg)
-		}
-	}()
-
-	// Subscriber 2: Slow subscriber
+	// Subscriber 2: Slow subscriber, reads nothing until publishing is over
 	slowSub := ps.Subscribe("news")
 	var slowMessages []string
-	slowWg := sync.WaitGroup{}
-	slowWg.Add(1)
-
-	go func() {
-		defer slowWg.Done()
-		for msg := range slowSub {
-			slowMessages = append(slowMessages, msg)
-			time.Sleep(200 * time.Millisecond) // Simulate slow processing
-		}
-	}()
 
-	// Publish messages
-	for i := 0; i < 10; i++ {
+	// Publish more messages than the slow subscriber's buffer can hold
+	const numMessages = 110
+	for i := 0; i < numMessages; i++ {
 		ps.Publish("news", fmt.Sprintf("Message %d", i))
-		time.Sleep(50 * time.Millisecond) // Simulate publishing rate
+		fastMessages = append(fastMessages, <-fastSub)
 	}
 
 	// Unsubscribe and shutdown
@@ -48,18 +28,21 @@ func TestSlowSubscriberHandling(t *testing.T) {
 	ps.Unsubscribe("news", slowSub)
 	ps.Shutdown()
 
-	// Wait for all subscribers to finish processing
-	fastWg.Wait()
-	slowWg.Wait()
+	// The slow subscriber catches up on whatever was buffered
+	for msg := range slowSub {
+		slowMessages = append(slowMessages, msg)
+	}
 
 	// Check results
-	if len(fastMessages) != 10 {
-		t.Errorf("Fast subscriber missed messages, received: %d, expected: 10", len(fastMessages))
+	if len(fastMessages) != numMessages {
+		t.Errorf("Fast subscriber missed messages, received: %d, expected: %d", len(fastMessages), numMessages)
 	}
 
-	if len(slowMessages) < 5 {
-		t.Errorf("Slow subscriber missed too many messages, received: %d, expected at least: 5", len(slowMessages))
-	} else {
-		t.Logf("Slow subscriber received %d messages (some may have been dropped).", len(slowMessages))
+	// The buffer holds the first 100 messages; the rest are dropped without blocking the publisher
+	if len(slowMessages) != 100 {
+		t.Fatalf("Slow subscriber received %d messages, expected 100", len(slowMessages))
+	}
+	if last := slowMessages[len(slowMessages)-1]; last != "Message 99" {
+		t.Errorf("Slow subscriber kept the wrong messages: last is %q, expected %q", last, "Message 99")
 	}
 }
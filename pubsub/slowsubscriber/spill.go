@@ -0,0 +1,300 @@
+package pubsub
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultSpillQuota is the disk quota of a spill queue when SpillConfig.MaxBytes is zero.
+const DefaultSpillQuota = 64 << 20
+
+// spillHeader is the size of the length prefix of every record in a spill segment
+const spillHeader = 4
+
+// Codec converts messages to and from bytes for the spill queue.
+type Codec[T any] interface {
+	Encode(message T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec encodes messages with encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(message T) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var message T
+	err := json.Unmarshal(data, &message)
+	return message, err
+}
+
+// SpillConfig configures a subscription created by SubscribeSpill.
+type SpillConfig[T any] struct {
+	Dir      string   // Directory of the queue files; os.TempDir() if empty
+	Codec    Codec[T] // Encodes spilled messages; JSONCodec if nil
+	MaxBytes int64    // Disk quota of the messages pending in the queue; DefaultSpillQuota if zero
+}
+
+// SubscribeSpill is like Subscribe, but messages that do not fit in the subscriber's buffer are written
+// to a queue on disk instead of being dropped, and fed back in order as the consumer catches up.
+// Messages are only dropped if the disk quota is exceeded or the codec fails.
+//
+// Spilled messages are discarded, and the files removed, once the subscription is closed.
+func (ps *PubSub[T]) SubscribeSpill(topic string, cfg SpillConfig[T]) (chan T, error) {
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec[T]{}
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = DefaultSpillQuota
+	}
+	queue, err := newSpillQueue(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscriber[T]{
+		ch:    make(chan T, 100), // In-memory buffer; the feeder moves messages from it to out
+		out:   make(chan T),
+		quit:  make(chan struct{}),
+		spill: queue,
+	}
+	go sub.feed()
+	ps.add(topic, sub)
+	return sub.out, nil
+}
+
+// feed forwards buffered messages, then spilled ones, to out until the subscription is closed.
+// Publish only spills while the buffer is full or older messages are still on disk, so every
+// buffered message is older than every spilled one.
+func (s *subscriber[T]) feed() {
+	defer s.spill.close()
+	defer close(s.out)
+
+	for {
+		message, ok := s.next()
+		if !ok {
+			return
+		}
+		select {
+		case s.out <- message:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// next returns the oldest pending message, waiting for one if needed. ok is false once the subscription is closed.
+func (s *subscriber[T]) next() (message T, ok bool) {
+	for {
+		select {
+		case message, ok = <-s.ch:
+			return message, ok
+		default:
+		}
+		if message, ok = s.spill.pop(); ok {
+			return message, true
+		}
+
+		select {
+		case message, ok = <-s.ch:
+			return message, ok
+		case <-s.spill.notify:
+		case <-s.quit:
+			return message, false
+		}
+	}
+}
+
+// spillQueue is a FIFO of encoded messages in a chain of segment files. Records are appended to the
+// last segment and read from the first, and a segment is removed as soon as it has been read to the end.
+// Disk space is therefore reclaimed without ever moving records, and a consumer that stays behind keeps
+// at most one partly read segment on disk besides its pending records.
+type spillQueue[T any] struct {
+	mu           sync.Mutex
+	dir          string
+	codec        Codec[T]
+	maxBytes     int64
+	segmentSize  int64           // Size at which the last segment is closed to new records
+	segments     []*spillSegment // Oldest first; records are appended to the last one
+	pendingBytes int64           // Bytes of the records not read yet
+	count        int             // Records not read yet
+	notify       chan struct{}   // Signals the feeder that a record was written (capacity 1)
+}
+
+// spillSegment is one file of a spill queue. Records are appended at writeOff and read at readOff.
+type spillSegment struct {
+	file     *os.File
+	readOff  int64
+	writeOff int64
+}
+
+func newSpillQueue[T any](cfg SpillConfig[T]) (*spillQueue[T], error) {
+	q := &spillQueue[T]{
+		dir:         cfg.Dir,
+		codec:       cfg.Codec,
+		maxBytes:    cfg.MaxBytes,
+		segmentSize: max(cfg.MaxBytes/4, 1),
+		notify:      make(chan struct{}, 1),
+	}
+	if err := q.addSegment(); err != nil { // Report an unusable directory when subscribing
+		return nil, err
+	}
+	return q, nil
+}
+
+// addSegment starts a new segment file for appending. Must be called with q.mu held, or before q is shared.
+func (q *spillQueue[T]) addSegment() error {
+	file, err := os.CreateTemp(q.dir, "spill-*.queue")
+	if err != nil {
+		return fmt.Errorf("slowsubscriber: create spill file: %w", err)
+	}
+	q.segments = append(q.segments, &spillSegment{file: file})
+	return nil
+}
+
+// pending reports whether messages are waiting on disk
+func (q *spillQueue[T]) pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count > 0
+}
+
+// len returns the number of messages waiting on disk
+func (q *spillQueue[T]) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+// push appends the message to the queue and reports whether it was stored.
+// It is dropped if it cannot be encoded or written, or if it would exceed the disk quota.
+func (q *spillQueue[T]) push(message T) bool {
+	data, err := q.codec.Encode(message)
+	if err != nil {
+		fmt.Printf("Dropping message that cannot be spilled: %v\n", err)
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record := make([]byte, spillHeader+len(data))
+	if q.pendingBytes+int64(len(record)) > q.maxBytes { // Only records not read yet count
+		fmt.Printf("Dropping message for a slow subscriber, spill quota exceeded: %v\n", message)
+		return false
+	}
+	if tail := q.segments[len(q.segments)-1]; tail.writeOff > 0 && tail.writeOff+int64(len(record)) > q.segmentSize {
+		if err := q.addSegment(); err != nil {
+			fmt.Printf("Dropping message that cannot be spilled: %v\n", err)
+			return false
+		}
+	}
+
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[spillHeader:], data)
+	tail := q.segments[len(q.segments)-1]
+	if _, err := tail.file.WriteAt(record, tail.writeOff); err != nil {
+		fmt.Printf("Dropping message that cannot be spilled: %v\n", err)
+		return false
+	}
+	tail.writeOff += int64(len(record))
+	q.pendingBytes += int64(len(record))
+	q.count++
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop removes the oldest message from the queue. ok is false if the queue is empty.
+// Records that cannot be read or decoded are dropped.
+func (q *spillQueue[T]) pop() (message T, ok bool) {
+	for {
+		data, ok := q.read()
+		if !ok {
+			return message, false
+		}
+		message, err := q.codec.Decode(data)
+		if err != nil {
+			fmt.Printf("Dropping spilled message that cannot be decoded: %v\n", err)
+			continue
+		}
+		return message, true
+	}
+}
+
+// read removes the oldest record from the queue and returns its data
+func (q *spillQueue[T]) read() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.count == 0 {
+		return nil, false
+	}
+
+	head := q.segments[0]
+	var header [spillHeader]byte
+	if _, err := head.file.ReadAt(header[:], head.readOff); err != nil {
+		fmt.Printf("Dropping spilled messages that cannot be read: %v\n", err)
+		q.reset()
+		return nil, false
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := head.file.ReadAt(data, head.readOff+spillHeader); err != nil {
+		fmt.Printf("Dropping spilled messages that cannot be read: %v\n", err)
+		q.reset()
+		return nil, false
+	}
+
+	head.readOff += spillHeader + int64(len(data))
+	q.pendingBytes -= spillHeader + int64(len(data))
+	q.count--
+	if head.readOff == head.writeOff {
+		if len(q.segments) > 1 {
+			remove(head) // Reclaim the segment once it has been read to the end
+			q.segments = q.segments[1:]
+		} else {
+			q.truncate(head) // The consumer has caught up; reuse the file
+		}
+	}
+	return data, true
+}
+
+// reset drops every pending record and keeps one empty segment. Must be called with q.mu held.
+func (q *spillQueue[T]) reset() {
+	for _, seg := range q.segments[1:] {
+		remove(seg)
+	}
+	q.segments = q.segments[:1]
+	q.truncate(q.segments[0])
+	q.pendingBytes, q.count = 0, 0
+}
+
+// truncate empties a segment so that it can be written from the start. Must be called with q.mu held.
+func (q *spillQueue[T]) truncate(seg *spillSegment) {
+	seg.readOff, seg.writeOff = 0, 0
+	if err := seg.file.Truncate(0); err != nil {
+		fmt.Printf("Failed to truncate spill file: %v\n", err)
+	}
+}
+
+// remove closes a segment file and deletes it
+func remove(seg *spillSegment) {
+	seg.file.Close()
+	os.Remove(seg.file.Name())
+}
+
+// close discards the spilled messages and removes the files
+func (q *spillQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, seg := range q.segments {
+		remove(seg)
+	}
+	q.segments = nil
+}
@@ -0,0 +1,177 @@
+package pubsub
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// countingCodec encodes ints as decimal strings and counts how many messages were spilled
+type countingCodec struct {
+	encoded int
+}
+
+func (c *countingCodec) Encode(message int) ([]byte, error) {
+	c.encoded++
+	return []byte(strconv.Itoa(message)), nil
+}
+
+func (c *countingCodec) Decode(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestSpillDeliversInOrder(t *testing.T) {
+	dir := t.TempDir()
+	codec := &countingCodec{}
+	ps := NewPubSub[int]()
+	ch, err := ps.SubscribeSpill("ticks", SpillConfig[int]{Dir: dir, Codec: codec})
+	if err != nil {
+		t.Fatalf("SubscribeSpill: %v", err)
+	}
+
+	// Three buffers' worth of messages, published before the consumer reads anything
+	const numMessages = 300
+	for i := 0; i < numMessages; i++ {
+		ps.Publish("ticks", i)
+	}
+	if codec.encoded < numMessages-101 {
+		t.Errorf("Expected at least %d messages to be spilled, got %d", numMessages-101, codec.encoded)
+	}
+
+	for i := 0; i < numMessages; i++ {
+		if msg := <-ch; msg != i {
+			t.Fatalf("Message %d: got %d", i, msg)
+		}
+	}
+
+	// Once the consumer has caught up, new messages are delivered from memory again
+	ps.Publish("ticks", numMessages)
+	if msg := <-ch; msg != numMessages {
+		t.Errorf("Expected %d after catching up, got %d", numMessages, msg)
+	}
+
+	ps.Unsubscribe("ticks", ch)
+	if _, ok := <-ch; ok {
+		t.Error("Expected the channel to be closed")
+	}
+	waitForEmptyDir(t, dir)
+}
+
+func TestSpillQuotaDropsMessages(t *testing.T) {
+	// Every message is a 3-digit JSON number plus a 4-byte length prefix, so 10 fit on disk
+	ps := NewPubSub[int]()
+	ch, err := ps.SubscribeSpill("ticks", SpillConfig[int]{Dir: t.TempDir(), MaxBytes: 70})
+	if err != nil {
+		t.Fatalf("SubscribeSpill: %v", err)
+	}
+
+	for i := 100; i < 400; i++ {
+		ps.Publish("ticks", i)
+	}
+
+	// The buffer and the 10 spilled messages arrive in order, plus possibly one held by the feeder
+	received := make([]int, 110)
+	for i := range received {
+		received[i] = <-ch
+	}
+	for i := 1; i < len(received); i++ {
+		if received[i] <= received[i-1] {
+			t.Fatalf("Messages out of order: %v", received)
+		}
+	}
+	if last := received[len(received)-1]; last >= 399 {
+		t.Errorf("Expected the messages over the quota to be dropped, last received %d", last)
+	}
+	ps.Unsubscribe("ticks", ch)
+}
+
+func TestSpillFileCreationError(t *testing.T) {
+	ps := NewPubSub[int]()
+	dir := filepath.Join(t.TempDir(), "missing")
+	if _, err := ps.SubscribeSpill("ticks", SpillConfig[int]{Dir: dir}); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+// waitForEmptyDir waits until the feeder has removed its spill files from dir
+func waitForEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("Spill files were not removed")
+}
+
+func TestSpillQuotaWithSteadilyLaggingConsumer(t *testing.T) {
+	// Room for about 20 pending records of up to 5 JSON digits plus the length prefix
+	dir := t.TempDir()
+	ps := NewPubSub[int]()
+	ch, err := ps.SubscribeSpill("ticks", SpillConfig[int]{Dir: dir, MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("SubscribeSpill: %v", err)
+	}
+
+	// Fill the buffer and spill a few messages, then drain the buffer so that only those are pending.
+	// From then on the consumer reads as fast as messages arrive, so a handful of records stay on disk
+	// and the queue never empties.
+	const backlog, numMessages = 105, 2000
+	for i := 0; i < backlog; i++ {
+		ps.Publish("ticks", i)
+	}
+	next := 0
+	receive := func() {
+		t.Helper()
+		select {
+		case msg := <-ch:
+			if msg != next {
+				t.Fatalf("Expected message %d, got %d", next, msg)
+			}
+			next++
+		case <-time.After(time.Second):
+			t.Fatalf("Message %d was dropped", next)
+		}
+	}
+	for next < 100 {
+		receive()
+	}
+	for i := backlog; i < numMessages; i++ {
+		ps.Publish("ticks", i)
+		receive()
+	}
+	if size := spillSize(t, dir); size > 250 { // MaxBytes plus one partly read segment
+		t.Errorf("Expected consumed segments to be removed, the spill files take %d bytes", size)
+	}
+
+	for next < numMessages {
+		receive()
+	}
+	ps.Unsubscribe("ticks", ch)
+}
+
+// spillSize returns the total size of the spill files in dir
+func spillSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		size += info.Size()
+	}
+	return size
+}
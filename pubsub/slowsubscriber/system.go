@@ -0,0 +1,17 @@
+package pubsub
+
+// EvictionTopic is the System topic on which an *Eviction is published for every evicted subscriber.
+const EvictionTopic = "$SYS.evictions"
+
+// LagAlertTopic is the System topic on which a *LagAlert is published, see WithLagMonitor.
+const LagAlertTopic = "$SYS.lag"
+
+// System returns the broker on which ps publishes events about itself, such as evictions and lag alerts.
+// It is created on first use and shut down with ps.
+func (ps *PubSub[T]) System() *PubSub[any] {
+	if sys := ps.system.Load(); sys != nil {
+		return sys
+	}
+	ps.system.CompareAndSwap(nil, NewPubSub[any](WithClock(ps.clock)))
+	return ps.system.Load()
+}
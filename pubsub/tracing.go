@@ -0,0 +1,153 @@
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HeaderTraceparent carries the W3C traceparent of the span that produced a message.
+const HeaderTraceparent = "traceparent"
+
+// TraceID identifies a whole trace across topics.
+type TraceID [16]byte
+
+// SpanID identifies a single span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the part of a span that propagates between messages.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set; a zero SpanContext means "no parent".
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ErrInvalidTraceparent is returned when a traceparent header cannot be parsed.
+var ErrInvalidTraceparent = errors.New("pubsub: invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value (version 00).
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// Span is a unit of work started by a Tracer.
+type Span interface {
+	Context() SpanContext           // Identity of the span, propagated to downstream messages
+	SetAttribute(key, value string) // Attach metadata to the span
+	RecordError(err error)          // Mark the span as failed
+	End()                           // Finish the span
+}
+
+// Tracer starts spans. parent is the zero SpanContext for root spans.
+type Tracer interface {
+	Start(name string, parent SpanContext) Span
+}
+
+// Trace installs publish and delivery middleware on ps that start a span for every
+// publish and a child span for every delivery, propagating them through the traceparent header.
+func Trace[T any](ps *PubSub[Envelope[T]], tracer Tracer) {
+	ps.Use(TracePublish[T](tracer))
+	ps.UseDelivery(TraceDelivery[T](tracer))
+}
+
+// TracePublish returns publish middleware that starts a span for each published envelope.
+// The span continues the trace found in the envelope's traceparent header, if any,
+// and replaces the header so that deliveries become its children.
+func TracePublish[T any](tracer Tracer) PublishMiddleware[Envelope[T]] {
+	return func(next PublishFunc[Envelope[T]]) PublishFunc[Envelope[T]] {
+		return func(topic string, message Envelope[T]) error {
+			return traced(tracer, "publish "+topic, topic, message, next)
+		}
+	}
+}
+
+// TraceDelivery returns delivery middleware that starts a child span of the publish span
+// for every subscriber. The subscriber receives the envelope with the delivery span as traceparent.
+func TraceDelivery[T any](tracer Tracer) DeliveryMiddleware[Envelope[T]] {
+	return func(next DeliverFunc[Envelope[T]]) DeliverFunc[Envelope[T]] {
+		return func(topic string, message Envelope[T]) error {
+			return traced(tracer, "deliver "+topic, topic, message, next)
+		}
+	}
+}
+
+// traced runs next inside a span whose parent is taken from the message's traceparent header
+func traced[T any](tracer Tracer, name, topic string, message Envelope[T], next func(string, Envelope[T]) error) error {
+	parent, _ := ParseTraceparent(message.Header(HeaderTraceparent)) // Missing or invalid: start a new trace
+	span := tracer.Start(name, parent)
+	defer span.End()
+
+	span.SetAttribute("messaging.destination", topic)
+	if message.ID != "" {
+		span.SetAttribute("messaging.message_id", message.ID)
+	}
+
+	err := next(topic, message.WithHeader(HeaderTraceparent, span.Context().Traceparent()))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// ContinueTrace copies the trace context of a received envelope onto an envelope about to be
+// published, so the new publish span becomes a child of the delivery that produced it.
+func ContinueTrace[T, U any](received Envelope[T], next Envelope[U]) Envelope[U] {
+	if tp := received.Header(HeaderTraceparent); tp != "" {
+		return next.WithHeader(HeaderTraceparent, tp)
+	}
+	return next
+}
+
+// newSpanContext returns a span context with a fresh span ID, inheriting the trace of parent if valid
+func newSpanContext(parent SpanContext) SpanContext {
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if parent.IsValid() {
+		sc.Sampled = parent.Sampled
+	} else {
+		randomBytes(sc.TraceID[:])
+	}
+	randomBytes(sc.SpanID[:])
+	return sc
+}
+
+// randomBytes fills b from crypto/rand
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("pubsub: failed to generate id: " + err.Error())
+	}
+}
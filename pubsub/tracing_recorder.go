@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a finished (or still running) span captured by a Recorder.
+type RecordedSpan struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext // Zero for root spans
+	Attributes map[string]string
+	Err        error
+	Start      time.Time
+	End        time.Time // Zero while the span is running
+}
+
+// Recorder is an in-memory Tracer that keeps every span it starts. It is meant for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start implements Tracer.
+func (r *Recorder) Start(name string, parent SpanContext) Span {
+	rec := &RecordedSpan{
+		Name:       name,
+		Context:    newSpanContext(parent),
+		Parent:     parent,
+		Attributes: make(map[string]string),
+		Start:      time.Now(),
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, rec)
+	r.mu.Unlock()
+	return &recorderSpan{recorder: r, rec: rec}
+}
+
+// Spans returns a copy of all spans started so far, in start order.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make([]RecordedSpan, len(r.spans))
+	for i, rec := range r.spans {
+		spans[i] = *rec
+		spans[i].Attributes = make(map[string]string, len(rec.Attributes))
+		for k, v := range rec.Attributes {
+			spans[i].Attributes[k] = v
+		}
+	}
+	return spans
+}
+
+// Reset discards all recorded spans.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// recorderSpan is the Span handed out by Recorder; all mutations go through the recorder's lock
+type recorderSpan struct {
+	recorder *Recorder
+	rec      *RecordedSpan
+}
+
+func (s *recorderSpan) Context() SpanContext {
+	return s.rec.Context
+}
+
+func (s *recorderSpan) SetAttribute(key, value string) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.rec.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.rec.Err = err
+}
+
+func (s *recorderSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	if s.rec.End.IsZero() {
+		s.rec.End = time.Now()
+	}
+}
@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("ParseTraceparent failed: %v", err)
+	}
+	if !sc.Sampled || sc.Traceparent() != header {
+		t.Errorf("Round trip mismatch: got %q", sc.Traceparent())
+	}
+
+	for _, bad := range []string{"", "00-abc-def-01", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestTracingAcrossTopics(t *testing.T) {
+	recorder := NewRecorder()
+	ps := NewPubSub[Envelope[string]]()
+	Trace(ps, recorder)
+
+	// orders -> billing -> shipping, each hop continuing the trace
+	billing := ps.Handle("orders", func(ctx context.Context, msg Envelope[string]) error {
+		return ps.Publish("billing", ContinueTrace(msg, Envelope[string]{Payload: "bill " + msg.Payload}))
+	})
+	shipping := ps.Handle("billing", func(ctx context.Context, msg Envelope[string]) error {
+		return ps.Publish("shipping", ContinueTrace(msg, Envelope[string]{Payload: "ship " + msg.Payload}))
+	})
+	final := ps.Subscribe("shipping")
+
+	if err := ps.Publish("orders", Envelope[string]{ID: "order-1", Payload: "order-1"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	received := <-final
+	billing.Stop()
+	shipping.Stop()
+	ps.Shutdown()
+
+	if received.Payload != "ship bill order-1" {
+		t.Fatalf("Unexpected payload: %q", received.Payload)
+	}
+
+	spans := recorder.Spans()
+	byID := make(map[SpanID]RecordedSpan, len(spans))
+	for _, span := range spans {
+		byID[span.Context.SpanID] = span
+	}
+
+	// Walk from the span the final subscriber saw back to the root
+	sc, err := ParseTraceparent(received.Header(HeaderTraceparent))
+	if err != nil {
+		t.Fatalf("Final message has no valid traceparent: %v", err)
+	}
+	var chain []string
+	for span, ok := byID[sc.SpanID]; ok; span, ok = byID[span.Parent.SpanID] {
+		if span.Context.TraceID != sc.TraceID {
+			t.Errorf("Span %q belongs to a different trace", span.Name)
+		}
+		chain = append([]string{span.Name}, chain...)
+		if !span.Parent.IsValid() {
+			break
+		}
+	}
+
+	expected := []string{"publish orders", "deliver orders", "publish billing", "deliver billing", "publish shipping", "deliver shipping"}
+	if len(chain) != len(expected) {
+		t.Fatalf("Unexpected span chain: %v", chain)
+	}
+	for i := range expected {
+		if chain[i] != expected[i] {
+			t.Errorf("Span %d: got %q, expected %q", i, chain[i], expected[i])
+		}
+	}
+
+	root := byID[sc.SpanID]
+	for root.Parent.IsValid() {
+		root = byID[root.Parent.SpanID]
+	}
+	if root.Attributes["messaging.message_id"] != "order-1" || root.End.IsZero() {
+		t.Errorf("Root span not recorded correctly: %+v", root)
+	}
+}